@@ -0,0 +1,79 @@
+package tsdns
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+// maxAliasDepth is the maximum number of aliases followed to reach a canonical record
+const maxAliasDepth = 8
+
+// AddAlias makes domain answer like the record of canonical
+//
+// The alias follows every change of the canonical record, such as a retarget, without
+// being updated itself. canonical is looked up in the tenant of the alias. Aliases of
+// aliases are followed up to 8 levels deep, an alias that would form a loop is rejected
+// with types.ErrAliasLoop.
+// Updates both repository and cache immediately
+func (s *Server) AddAlias(domain, canonical string) error {
+	return s.AddAliasContext(context.Background(), domain, canonical)
+}
+
+// AddAliasContext is like AddAlias but uses the deadline, tenant and actor of ctx
+func (s *Server) AddAliasContext(ctx context.Context, domain, canonical string) error {
+	return s.applyOne(ctx, types.Change{
+		Op: types.ChangeCreate,
+		Record: &types.Record{
+			Domain:  domain,
+			AliasOf: canonical,
+		},
+	})
+}
+
+// followAliases follows the aliases starting at record to the record answering for them
+//
+// find returns the record answering for a domain, or nil. It returns nil if an alias
+// points to a domain without a record. A disabled alias is returned as is, it answers
+// like any disabled record
+func followAliases(record *types.Record, find func(domain string) (*types.Record, error)) (*types.Record, error) {
+	chain := []string{record.Domain}
+	for record.Alias() && record.Enabled() {
+		if slices.Contains(chain, record.AliasOf) || len(chain) > maxAliasDepth {
+			chain = append(chain, record.AliasOf)
+			return nil, fmt.Errorf("%w: %s", types.ErrAliasLoop, strings.Join(chain, " -> "))
+		}
+
+		next, err := find(record.AliasOf)
+		if err != nil || next == nil {
+			return nil, err
+		}
+		chain = append(chain, next.Domain)
+		record = next
+	}
+	return record, nil
+}
+
+// checkAliases rejects changes to a tenant that leave an alias they store in a loop
+// The changes are checked against the cache as it will be once they are applied
+func (s *Server) checkAliases(tenant string, changes []types.Change) error {
+	var next *recordCache
+	for i, change := range changes {
+		if change.Record == nil || !change.Record.Alias() {
+			continue
+		}
+		if next == nil {
+			next = s.cache.clone()
+			for _, change := range changes {
+				next.apply(tenant, change)
+			}
+		}
+		if _, err := followAliases(change.Record, next.finder(tenant)); err != nil {
+			return changeError(changes, i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package tsdns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+func TestFollowAliases(t *testing.T) {
+	alias := func(domain, canonical string) *types.Record {
+		return &types.Record{Domain: domain, AliasOf: canonical}
+	}
+	// chain returns aliases from 0.example.com down to n.example.com, which holds the target
+	chain := func(n int) []*types.Record {
+		records := []*types.Record{{Domain: fmt.Sprintf("%d.example.com", n), Target: "192.0.2.1"}}
+		for i := 0; i < n; i++ {
+			records = append(records, alias(fmt.Sprintf("%d.example.com", i), fmt.Sprintf("%d.example.com", i+1)))
+		}
+		return records
+	}
+
+	tests := []struct {
+		name    string
+		records []*types.Record
+		// start is the queried domain, a.example.com if empty
+		start string
+		// want is the domain of the record reached, "" for none
+		want    string
+		wantErr error
+	}{
+		{
+			name:    "record without alias",
+			records: []*types.Record{{Domain: "a.example.com", Target: "192.0.2.1"}},
+			want:    "a.example.com",
+		},
+		{
+			name:    "single alias",
+			records: []*types.Record{alias("a.example.com", "b.example.com"), {Domain: "b.example.com", Target: "192.0.2.1"}},
+			want:    "b.example.com",
+		},
+		{
+			name:    "alias to a missing domain",
+			records: []*types.Record{alias("a.example.com", "b.example.com")},
+		},
+		{
+			name:    "disabled alias",
+			records: []*types.Record{{Domain: "a.example.com", AliasOf: "b.example.com", Disabled: true}, {Domain: "b.example.com", Target: "192.0.2.1"}},
+			want:    "a.example.com",
+		},
+		{
+			name:    "loop of two",
+			records: []*types.Record{alias("a.example.com", "b.example.com"), alias("b.example.com", "a.example.com")},
+			wantErr: types.ErrAliasLoop,
+		},
+		{
+			name:    "loop further down the chain",
+			records: []*types.Record{alias("a.example.com", "b.example.com"), alias("b.example.com", "c.example.com"), alias("c.example.com", "b.example.com")},
+			wantErr: types.ErrAliasLoop,
+		},
+		{
+			name:    "maximum depth",
+			records: chain(maxAliasDepth),
+			start:   "0.example.com",
+			want:    fmt.Sprintf("%d.example.com", maxAliasDepth),
+		},
+		{
+			name:    "beyond the maximum depth",
+			records: chain(maxAliasDepth + 1),
+			start:   "0.example.com",
+			wantErr: types.ErrAliasLoop,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			domain := tt.start
+			if domain == "" {
+				domain = "a.example.com"
+			}
+			cache := newRecordCache([]string{""}, tt.records)
+			start, ok := cache.lookup(domain)
+			if !ok {
+				t.Fatalf("%s is not cached", domain)
+			}
+
+			record, err := followAliases(start, cache.finder(""))
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("followAliases error = %v, want %v", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatalf("followAliases error: %v", err)
+			case tt.want == "" && record != nil:
+				t.Fatalf("followAliases reached %s, want no record", record.Domain)
+			case tt.want != "" && (record == nil || record.Domain != tt.want):
+				t.Fatalf("followAliases reached %+v, want %s", record, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddAliasRejectsLoops(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+	mustCreate(t, s, &types.Record{Domain: "a.example.com", Target: "192.0.2.1"})
+
+	tests := []struct {
+		domain, canonical string
+		want              error
+	}{
+		{"b.example.com", "a.example.com", nil},
+		{"c.example.com", "b.example.com", nil},
+		{"d.example.com", "d.example.com", types.ErrInvalidRecord},
+		{"e.example.com", "f.example.com", nil},
+		{"f.example.com", "e.example.com", types.ErrAliasLoop},
+	}
+	for _, tt := range tests {
+		t.Run(tt.domain+" -> "+tt.canonical, func(t *testing.T) {
+			if err := s.AddAliasContext(ctx, tt.domain, tt.canonical); !errors.Is(err, tt.want) {
+				t.Fatalf("AddAlias error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+	if response, found := s.resolve("c.example.com"); !found || response != "192.0.2.1" {
+		t.Errorf("c.example.com answered %q, want the target of a.example.com", response)
+	}
+}
@@ -0,0 +1,156 @@
+package tsdns
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// AnswerCacheStats describes the state of the answer cache
+type AnswerCacheStats struct {
+	// Size is the number of entries currently cached
+	Size int
+	// Capacity is the maximum number of entries
+	Capacity int
+	// Hits is the number of lookups answered from the cache
+	Hits uint64
+	// Misses is the number of lookups not found or expired in the cache
+	Misses uint64
+	// Evictions is the number of entries dropped to make room for new ones
+	Evictions uint64
+}
+
+// answerEntry is a cached answer for a domain
+type answerEntry struct {
+	domain    string
+	response  string
+	found     bool
+	expiresAt time.Time
+}
+
+// answerCache is a bounded LRU cache for misses and answers from slow sources
+// Every entry carries its own expiry
+type answerCache struct {
+	mu        sync.Mutex
+	capacity  int
+	entries   *list.List
+	items     map[string]*list.Element
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+// newAnswerCache creates an answer cache holding at most capacity entries
+func newAnswerCache(capacity int) *answerCache {
+	return &answerCache{
+		capacity: capacity,
+		entries:  list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached answer for a domain
+func (c *answerCache) get(domain string) (response string, found bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, exists := c.items[domain]
+	if !exists {
+		c.misses++
+		return "", false, false
+	}
+
+	entry := elem.Value.(*answerEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.removeElement(elem)
+		c.misses++
+		return "", false, false
+	}
+
+	c.entries.MoveToFront(elem)
+	c.hits++
+	return entry.response, entry.found, true
+}
+
+// set caches an answer for a domain for the given duration
+func (c *answerCache) set(domain, response string, found bool, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &answerEntry{
+		domain:    domain,
+		response:  response,
+		found:     found,
+		expiresAt: time.Now().Add(ttl),
+	}
+
+	if elem, exists := c.items[domain]; exists {
+		elem.Value = entry
+		c.entries.MoveToFront(elem)
+		return
+	}
+
+	c.items[domain] = c.entries.PushFront(entry)
+	for c.entries.Len() > c.capacity {
+		c.removeElement(c.entries.Back())
+		c.evictions++
+	}
+}
+
+// remove drops the cached answer for a domain
+func (c *answerCache) remove(domain string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, exists := c.items[domain]; exists {
+		c.removeElement(elem)
+	}
+}
+
+// flush drops all cached answers
+func (c *answerCache) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+// stats returns the current cache statistics
+func (c *answerCache) stats() AnswerCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return AnswerCacheStats{
+		Size:      c.entries.Len(),
+		Capacity:  c.capacity,
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+	}
+}
+
+func (c *answerCache) removeElement(elem *list.Element) {
+	c.entries.Remove(elem)
+	delete(c.items, elem.Value.(*answerEntry).domain)
+}
+
+// AnswerCacheStats returns the answer cache statistics
+// A zero value is returned if the answer cache is not enabled
+func (s *Server) AnswerCacheStats() AnswerCacheStats {
+	if s.answers == nil {
+		return AnswerCacheStats{}
+	}
+	return s.answers.stats()
+}
+
+// FlushAnswerCache drops all cached misses and external answers
+func (s *Server) FlushAnswerCache() {
+	if s.answers != nil {
+		s.answers.flush()
+	}
+}
@@ -0,0 +1,149 @@
+package tsdns
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+func TestAnswerCacheLRU(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		// ops are "set <domain>" or "get <domain>", run in order
+		ops []string
+		// want are the domains cached afterwards
+		want      []string
+		evictions uint64
+	}{
+		{
+			name:     "within capacity",
+			capacity: 3,
+			ops:      []string{"set a", "set b", "set c"},
+			want:     []string{"a", "b", "c"},
+		},
+		{
+			name:      "least recently set is evicted",
+			capacity:  2,
+			ops:       []string{"set a", "set b", "set c"},
+			want:      []string{"b", "c"},
+			evictions: 1,
+		},
+		{
+			name:      "get refreshes an entry",
+			capacity:  2,
+			ops:       []string{"set a", "set b", "get a", "set c"},
+			want:      []string{"a", "c"},
+			evictions: 1,
+		},
+		{
+			name:     "set refreshes an entry",
+			capacity: 2,
+			ops:      []string{"set a", "set b", "set a", "set c"},
+			want:     []string{"a", "c"},
+			// replacing an entry evicts nothing
+			evictions: 1,
+		},
+		{
+			name:      "miss changes nothing",
+			capacity:  1,
+			ops:       []string{"set a", "get b", "set c"},
+			want:      []string{"c"},
+			evictions: 1,
+		},
+		{
+			name:      "capacity of one",
+			capacity:  1,
+			ops:       []string{"set a", "set b", "set c", "set d"},
+			want:      []string{"d"},
+			evictions: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newAnswerCache(tt.capacity)
+			for _, op := range tt.ops {
+				action, domain, _ := strings.Cut(op, " ")
+				switch action {
+				case "set":
+					c.set(domain, domain+":9987", true, time.Hour)
+				case "get":
+					c.get(domain)
+				}
+			}
+
+			var cached []string
+			for domain := range c.items {
+				cached = append(cached, domain)
+			}
+			slices.Sort(cached)
+			if !slices.Equal(cached, tt.want) {
+				t.Errorf("cached %v, want %v", cached, tt.want)
+			}
+			if stats := c.stats(); stats.Evictions != tt.evictions || stats.Size != len(tt.want) {
+				t.Errorf("stats = %+v, want %d entries and %d evictions", stats, len(tt.want), tt.evictions)
+			}
+		})
+	}
+}
+
+func TestAnswerCacheExpiry(t *testing.T) {
+	const (
+		before = "192.0.2.1:9987"
+		after  = "192.0.2.2:9987"
+	)
+
+	tests := []struct {
+		name             string
+		ttl, negativeTTL time.Duration
+		// exists stores the record before the first lookup, otherwise it is created after it
+		exists bool
+		// wait is the time between the two lookups
+		wait time.Duration
+		// want is the response of the second lookup
+		want string
+	}{
+		{name: "answer cached", ttl: time.Hour, negativeTTL: time.Hour, exists: true, want: before},
+		{name: "answer expired", ttl: 20 * time.Millisecond, negativeTTL: time.Hour, exists: true, wait: 50 * time.Millisecond, want: after},
+		{name: "miss cached", ttl: time.Hour, negativeTTL: time.Hour, want: ""},
+		{name: "miss expired", ttl: time.Hour, negativeTTL: 20 * time.Millisecond, wait: 50 * time.Millisecond, want: after},
+		{name: "answer outlives the negative ttl", ttl: time.Hour, negativeTTL: 20 * time.Millisecond, exists: true, wait: 50 * time.Millisecond, want: before},
+		{name: "misses not cached", ttl: time.Hour, want: after},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, func(b *ServerBuilder) *ServerBuilder {
+				return b.WithPassThrough().WithAnswerCache(8, tt.ttl, tt.negativeTTL)
+			})
+			// records are written to the repository only, so lookups go through the answer cache
+			ctx := types.WithTenant(context.Background(), "")
+			record := &types.Record{Domain: "a.example.com", Target: "192.0.2.1", Port: 9987}
+			if tt.exists {
+				if err := s.repository.Create(ctx, record); err != nil {
+					t.Fatalf("Create error: %v", err)
+				}
+			}
+
+			first, _ := s.resolve("a.example.com")
+			if want := map[bool]string{true: before, false: ""}[tt.exists]; first != want {
+				t.Fatalf("first lookup = %q, want %q", first, want)
+			}
+
+			record.Target = "192.0.2.2"
+			if err := s.repository.Upsert(ctx, record); err != nil {
+				t.Fatalf("Upsert error: %v", err)
+			}
+			time.Sleep(tt.wait)
+
+			if second, _ := s.resolve("a.example.com"); second != tt.want {
+				t.Errorf("second lookup = %q, want %q", second, tt.want)
+			}
+		})
+	}
+}
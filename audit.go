@@ -0,0 +1,88 @@
+package tsdns
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+// reaperActor is the audit actor of records removed by the reaper
+const reaperActor = "tsdns-reaper"
+
+// History returns the audit trail of a domain, oldest first
+// Changes are attributed to the actor set on their context with types.WithActor.
+// It returns types.ErrNotSupported if the repository keeps no audit trail
+func (s *Server) History(domain string) ([]*types.AuditEntry, error) {
+	return s.HistoryContext(context.Background(), domain)
+}
+
+// HistoryContext is like History but uses the deadline, tenant and actor of ctx
+func (s *Server) HistoryContext(ctx context.Context, domain string) ([]*types.AuditEntry, error) {
+	audited, ok := s.repository.(types.AuditRepository)
+	if !ok {
+		return nil, types.ErrNotSupported
+	}
+
+	ctx, cancel := s.repoContext(ctx)
+	defer cancel()
+
+	return audited.History(ctx, domain)
+}
+
+// RollbackRecord restores the instance, target, port, alias and metadata a domain had after an audit entry
+//
+// The entry is picked by its ID from the audit trail of the domain, an entry deleting the record
+// cannot be rolled back to. The record with the same validity window is updated, or created again
+// if it was deleted, keeping the lease of the current record. The rollback is audited like any update
+func (s *Server) RollbackRecord(domain string, entryID int64) (*types.Record, error) {
+	return s.RollbackRecordContext(context.Background(), domain, entryID)
+}
+
+// RollbackRecordContext is like RollbackRecord but uses the deadline, tenant and actor of ctx
+func (s *Server) RollbackRecordContext(ctx context.Context, domain string, entryID int64) (*types.Record, error) {
+	history, err := s.HistoryContext(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry *types.AuditEntry
+	for _, e := range history {
+		if e.ID == entryID {
+			entry = e
+			break
+		}
+	}
+	switch {
+	case entry == nil:
+		return nil, fmt.Errorf("%w: %s has no audit entry %d", types.ErrNotFound, domain, entryID)
+	case entry.New == nil:
+		return nil, fmt.Errorf("%w: audit entry %d of %s deleted the record", types.ErrInvalidQuery, entryID, domain)
+	}
+	prior := entry.New
+
+	record := &types.Record{
+		InstanceID:  prior.InstanceID,
+		Domain:      prior.Domain,
+		Target:      prior.Target,
+		Port:        prior.Port,
+		ExpiresAt:   prior.ExpiresAt,
+		ValidFrom:   prior.ValidFrom,
+		ValidUntil:  prior.ValidUntil,
+		Labels:      prior.Labels,
+		Description: prior.Description,
+		Owner:       prior.Owner,
+		Disabled:    prior.Disabled,
+		AliasOf:     prior.AliasOf,
+	}
+	for _, current := range s.cache.domain(s.tenant(ctx), domain) {
+		if current.SameWindow(record) {
+			record.ExpiresAt = current.ExpiresAt
+		}
+	}
+
+	if err = s.applyOne(ctx, types.Change{Op: types.ChangeUpsert, Record: record}); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
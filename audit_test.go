@@ -0,0 +1,88 @@
+package tsdns
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+// mustHistory returns the audit trail of a domain
+func mustHistory(t *testing.T, s *Server, domain string) []*types.AuditEntry {
+	t.Helper()
+	history, err := s.History(domain)
+	if err != nil {
+		t.Fatalf("History(%s) error: %v", domain, err)
+	}
+	return history
+}
+
+func TestRollbackRecord(t *testing.T) {
+	s := newTestServer(t, nil)
+	mustCreate(t, s, &types.Record{InstanceID: 1, Domain: "a.example.com", Target: "192.0.2.1", Port: 9987})
+	if err := s.SetRecord("a.example.com", "192.0.2.2", 9988); err != nil {
+		t.Fatalf("SetRecord error: %v", err)
+	}
+
+	history := mustHistory(t, s, "a.example.com")
+	if len(history) != 2 {
+		t.Fatalf("a.example.com has %d audit entries, want 2", len(history))
+	}
+	record, err := s.RollbackRecord("a.example.com", history[0].ID)
+	if err != nil {
+		t.Fatalf("RollbackRecord error: %v", err)
+	}
+	if record.Target != "192.0.2.1" || record.Port != 9987 || record.InstanceID != 1 {
+		t.Fatalf("RollbackRecord = %s:%d of instance %d, want the created 192.0.2.1:9987 of instance 1",
+			record.Target, record.Port, record.InstanceID)
+	}
+	mustResolve(t, s, "a.example.com", "192.0.2.1:9987")
+
+	// the rollback is audited like any update
+	if history = mustHistory(t, s, "a.example.com"); len(history) != 3 {
+		t.Fatalf("a.example.com has %d audit entries after the rollback, want 3", len(history))
+	}
+}
+
+func TestRollbackDeletedRecord(t *testing.T) {
+	s := newTestServer(t, nil)
+	mustCreate(t, s, &types.Record{Domain: "a.example.com", Target: "192.0.2.1", Port: 9987})
+	if err := s.RemoveRecord("a.example.com"); err != nil {
+		t.Fatalf("RemoveRecord error: %v", err)
+	}
+
+	history := mustHistory(t, s, "a.example.com")
+	if len(history) != 2 || history[1].New != nil {
+		t.Fatalf("audit trail of a.example.com = %v, want the create and the delete", history)
+	}
+
+	// the delete itself has no record to go back to
+	if _, err := s.RollbackRecord("a.example.com", history[1].ID); !errors.Is(err, types.ErrInvalidQuery) {
+		t.Fatalf("RollbackRecord to the delete error = %v, want ErrInvalidQuery", err)
+	}
+	mustResolve(t, s, "a.example.com", "")
+
+	// rolling back to the state before the delete creates the record again
+	if _, err := s.RollbackRecord("a.example.com", history[0].ID); err != nil {
+		t.Fatalf("RollbackRecord error: %v", err)
+	}
+	mustResolve(t, s, "a.example.com", "192.0.2.1:9987")
+}
+
+func TestRollbackEntryOfAnotherDomain(t *testing.T) {
+	s := newTestServer(t, nil)
+	mustCreate(t, s,
+		&types.Record{Domain: "a.example.com", Target: "192.0.2.1", Port: 9987},
+		&types.Record{Domain: "b.example.com", Target: "192.0.2.2", Port: 9987},
+	)
+
+	other := mustHistory(t, s, "b.example.com")
+	if _, err := s.RollbackRecord("a.example.com", other[0].ID); !errors.Is(err, types.ErrNotFound) {
+		t.Fatalf("RollbackRecord to an entry of b.example.com error = %v, want ErrNotFound", err)
+	}
+	if _, err := s.RollbackRecord("a.example.com", 1000); !errors.Is(err, types.ErrNotFound) {
+		t.Fatalf("RollbackRecord to an unknown entry error = %v, want ErrNotFound", err)
+	}
+	mustResolve(t, s, "a.example.com", "192.0.2.1:9987")
+	mustResolve(t, s, "b.example.com", "192.0.2.2:9987")
+}
@@ -1,42 +1,361 @@
 package tsdns
 
 import (
-	"fmt"
 	"github.com/honeybbq/tsdns-go/types"
+	"slices"
+	"sync"
 	"time"
 )
 
-// loadCache loads records from repository into memory cache
+const (
+	// cacheRefreshInterval is how often the cache is reloaded from the repository
+	cacheRefreshInterval = 30 * time.Second
+	// cacheRetryInterval is how often a degraded server retries the repository
+	cacheRetryInterval = 5 * time.Second
+)
+
+// recordCache holds the records and maintenance windows served by the server
+//
+// Writers modify it in place under mu, but never write into the per-domain slices or the
+// windows slice, they replace them. Readers may therefore keep using what they read after
+// releasing mu. A domain may hold several records with different validity windows, the
+// active one is picked at lookup time
+type recordCache struct {
+	mu sync.RWMutex
+	// tenants are the served tenants in order of precedence
+	tenants []string
+	records map[cacheKey][]*types.Record
+	windows []*types.Maintenance
+
+	// reloadMu serializes reloads from the repository
+	reloadMu sync.Mutex
+	// reloading is set while a reload reads the repository. The writes made meanwhile are
+	// kept in pending and replayed on the reloaded contents, as the reload may have missed them
+	reloading bool
+	pending   []func(c *recordCache)
+}
+
+// cacheKey identifies the records of a domain within a tenant
+type cacheKey struct {
+	tenant string
+	domain string
+}
+
+// newRecordCache builds a cache from the given records
+// Records of tenants not listed are left out
+func newRecordCache(tenants []string, records []*types.Record) *recordCache {
+	c := &recordCache{tenants: tenants, records: make(map[cacheKey][]*types.Record, len(records))}
+	for _, r := range records {
+		if slices.Contains(tenants, r.Tenant) {
+			c.put(r)
+		}
+	}
+	return c
+}
+
+// lookup returns the record currently answering for a domain
+// Records whose lease or window has ended are treated as missing until the reaper removes them.
+// A domain held by several tenants is answered by the first tenant with an active record
+func (c *recordCache) lookup(domain string) (*types.Record, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := time.Now()
+	for _, tenant := range c.tenants {
+		if record := types.Active(c.records[cacheKey{tenant, domain}], now); record != nil {
+			return record, true
+		}
+	}
+	return nil, false
+}
+
+// domain returns all records of a domain within a tenant
+func (c *recordCache) domain(tenant, domain string) []*types.Record {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.records[cacheKey{tenant, domain}]
+}
+
+// finder returns a function finding the record currently answering for a domain of a tenant, or nil
+func (c *recordCache) finder(tenant string) func(domain string) (*types.Record, error) {
+	return func(domain string) (*types.Record, error) {
+		return types.Active(c.domain(tenant, domain), time.Now()), nil
+	}
+}
+
+// expiredInstances returns the instances of a tenant holding a record whose lease has ended
+func (c *recordCache) expiredInstances(tenant string, now time.Time) []int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var instanceIDs []int64
+	for key, domainRecords := range c.records {
+		if key.tenant != tenant {
+			continue
+		}
+		for _, r := range domainRecords {
+			if r.InstanceID != 0 && r.Expired(now) && !slices.Contains(instanceIDs, r.InstanceID) {
+				instanceIDs = append(instanceIDs, r.InstanceID)
+			}
+		}
+	}
+	return instanceIDs
+}
+
+// clone returns a copy of the records of the cache, such as to check changes before applying them
+// The per-domain slices are shared, modifications must replace them instead of writing into them
+func (c *recordCache) clone() *recordCache {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	records := make(map[cacheKey][]*types.Record, len(c.records))
+	for key, domainRecords := range c.records {
+		records[key] = domainRecords
+	}
+	return &recordCache{tenants: c.tenants, records: records}
+}
+
+// put stores a copy of the record so later changes by the repository do not leak into the cache
+// It replaces the record with the same ID or the same tenant, domain and validity window.
+// put, remove, update and apply change the cache in place, on the cache of the server they
+// must run within modify
+func (c *recordCache) put(r *types.Record) {
+	key := cacheKey{r.Tenant, r.Domain}
+	existing := c.records[key]
+	domainRecords := make([]*types.Record, 0, len(existing)+1)
+	for _, e := range existing {
+		if !e.SameWindow(r) && (r.ID == 0 || e.ID != r.ID) {
+			domainRecords = append(domainRecords, e)
+		}
+	}
+
+	if r.DeletedAt == nil {
+		domainRecords = append(domainRecords, r.Clone())
+	}
+
+	if len(domainRecords) == 0 {
+		delete(c.records, key)
+		return
+	}
+	c.records[key] = domainRecords
+}
+
+// remove drops all records matching the predicate
+func (c *recordCache) remove(match func(r *types.Record) bool) {
+	for key, domainRecords := range c.records {
+		kept := make([]*types.Record, 0, len(domainRecords))
+		for _, r := range domainRecords {
+			if !match(r) {
+				kept = append(kept, r)
+			}
+		}
+		switch {
+		case len(kept) == 0:
+			delete(c.records, key)
+		case len(kept) != len(domainRecords):
+			c.records[key] = kept
+		}
+	}
+}
+
+// update replaces matching records with a modified copy
+func (c *recordCache) update(match func(r *types.Record) bool, fn func(r *types.Record)) {
+	for key, domainRecords := range c.records {
+		var updated []*types.Record
+		for i, r := range domainRecords {
+			if !match(r) {
+				continue
+			}
+			if updated == nil {
+				updated = append([]*types.Record(nil), domainRecords...)
+			}
+			record := *r
+			fn(&record)
+			updated[i] = &record
+		}
+		if updated != nil {
+			c.records[key] = updated
+		}
+	}
+}
+
+// apply mirrors a repository change made in a tenant in the cache
+func (c *recordCache) apply(tenant string, change types.Change) {
+	switch change.Op {
+	case types.ChangeCreate, types.ChangeUpdate, types.ChangeUpsert:
+		record := change.Record
+		if record.Tenant != tenant {
+			// the repository has not assigned the tenant yet, such as when checking aliases
+			record = record.Clone()
+			record.Tenant = tenant
+		}
+		c.put(record)
+	case types.ChangeDelete:
+		delete(c.records, cacheKey{tenant, change.Domain})
+	case types.ChangeDeleteInstance:
+		c.remove(func(r *types.Record) bool {
+			return r.Tenant == tenant && r.InstanceID == change.InstanceID
+		})
+	case types.ChangeDeleteID:
+		c.remove(func(r *types.Record) bool {
+			return r.Tenant == tenant && r.ID == change.ID
+		})
+	}
+}
+
+// maintenance returns the cached maintenance windows
+// The slice is never written to, writers replace it
+func (c *recordCache) maintenance() []*types.Maintenance {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.windows
+}
+
+// modify runs fn on the cache under mu
+// While a reload is in progress fn is kept to be replayed on the reloaded contents,
+// so fn must leave the cache the same when run again
+func (c *recordCache) modify(fn func(c *recordCache)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fn(c)
+	if c.reloading {
+		c.pending = append(c.pending, fn)
+	}
+}
+
+// startReload starts recording the writes made while the repository is read
+func (c *recordCache) startReload() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reloading, c.pending = true, nil
+}
+
+// replace swaps in the given records and maintenance windows together
+// Writes made since startReload are replayed on them. Windows of tenants not served are dropped
+func (c *recordCache) replace(records []*types.Record, windows []*types.Maintenance) {
+	next := newRecordCache(c.tenants, records)
+	windows = slices.DeleteFunc(slices.Clone(windows), func(window *types.Maintenance) bool {
+		return !slices.Contains(c.tenants, window.Tenant)
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.records, c.windows = next.records, windows
+	for _, fn := range c.pending {
+		fn(c)
+	}
+	c.reloading, c.pending = false, nil
+}
+
+// cancelReload stops recording writes after a failed reload
+func (c *recordCache) cancelReload() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reloading, c.pending = false, nil
+}
+
+// loadCache loads records and maintenance windows from repository into memory cache
+// On failure the server is marked degraded and keeps serving the current cache
 func (s *Server) loadCache() error {
-	records, err := s.repository.Find()
+	s.cache.reloadMu.Lock()
+	defer s.cache.reloadMu.Unlock()
+
+	// writes made while the repository is read may be missing from what is read,
+	// they are replayed instead of reading the repository again
+	s.cache.startReload()
+	records, err := s.findServed()
+	var windows []*types.Maintenance
+	if err == nil {
+		windows, err = s.findMaintenance()
+	}
 	if err != nil {
+		s.cache.cancelReload()
+		s.setStatus(StatusDegraded)
 		return err
 	}
 
-	newCache := make(map[string]*types.Record)
-	for _, r := range records {
-		newCache[r.Domain] = r
+	s.cache.replace(records, windows)
+	s.setStatus(StatusHealthy)
+
+	if err = s.saveSnapshot(records, windows); err != nil {
+		s.logger.Warn("Snapshot save error: %v\n", err)
 	}
+	return nil
+}
 
-	s.mu.Lock()
-	s.cache = newCache
-	s.mu.Unlock()
+// findServed reads the live records of all served tenants from the repository
+func (s *Server) findServed() ([]*types.Record, error) {
+	var records []*types.Record
+	for _, tenant := range s.tenants {
+		ctx, cancel := s.repoContext(types.WithTenant(s.ctx, tenant))
+		tenantRecords, err := s.repository.Find(ctx)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, tenantRecords...)
+	}
+	return records, nil
+}
 
-	return nil
+// updateCache applies changes made in a tenant to the cache
+// Changes of tenants the server does not serve are ignored
+func (s *Server) updateCache(tenant string, changes ...types.Change) {
+	if len(changes) == 0 || !slices.Contains(s.tenants, tenant) {
+		return
+	}
+	s.cache.modify(func(c *recordCache) {
+		for _, change := range changes {
+			c.apply(tenant, change)
+		}
+	})
+	s.invalidateAnswers(changes)
+}
+
+// invalidateAnswers drops answer cache entries made stale by the given changes
+func (s *Server) invalidateAnswers(changes []types.Change) {
+	if s.answers == nil {
+		return
+	}
+	for _, change := range changes {
+		switch change.Op {
+		case types.ChangeCreate, types.ChangeUpdate, types.ChangeUpsert:
+			s.answers.remove(change.Record.Domain)
+		case types.ChangeDelete:
+			s.answers.remove(change.Domain)
+		default:
+			// the affected domains are unknown, so drop everything
+			s.answers.flush()
+			return
+		}
+	}
 }
 
 // cacheUpdater periodically updates the in-memory cache
-// Runs every 30 seconds to ensure data consistency
+// Runs every 30 seconds to ensure data consistency, and every 5 seconds while degraded
 func (s *Server) cacheUpdater() {
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(cacheRefreshInterval)
 	defer ticker.Stop()
+	retry := time.NewTicker(cacheRetryInterval)
+	defer retry.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
 			if err := s.loadCache(); err != nil {
-				fmt.Printf("Cache update error: %v\n", err)
+				s.logger.Error("Cache update error: %v\n", err)
+			}
+		case <-retry.C:
+			if s.Status() != StatusDegraded {
+				continue
+			}
+			if err := s.loadCache(); err != nil {
+				s.logger.Debug("Repository still unavailable: %v\n", err)
+				continue
 			}
+			s.logger.Info("Repository available again, cache refreshed\n")
 		case <-s.ctx.Done():
 			return
 		}
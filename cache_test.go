@@ -0,0 +1,133 @@
+package tsdns
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/honeybbq/tsdns-go/repository/file"
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+// stallingRepository reads the records on Find, then waits for release before returning them,
+// like a slow repository whose read misses the writes made meanwhile
+type stallingRepository struct {
+	types.RecordRepository
+	finds   atomic.Int32
+	reading chan struct{}
+	release chan struct{}
+}
+
+func (r *stallingRepository) Find(ctx context.Context) ([]*types.Record, error) {
+	records, err := r.RecordRepository.Find(ctx)
+	if r.finds.Add(1) == 1 {
+		close(r.reading)
+		<-r.release
+	}
+	return records, err
+}
+
+func TestLoadCacheConcurrentWrites(t *testing.T) {
+	inner, err := file.NewRepository(filepath.Join(t.TempDir(), "records.bin"))
+	if err != nil {
+		t.Fatalf("NewRepository error: %v", err)
+	}
+	repo := &stallingRepository{RecordRepository: inner, reading: make(chan struct{}), release: make(chan struct{})}
+	s, err := NewServer("127.0.0.1").WithRepository(repo).Build()
+	if err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+	t.Cleanup(func() {
+		s.Close()
+	})
+	mustCreate(t, s, &types.Record{Domain: "a.example.com", Target: "192.0.2.1", Port: 9987})
+
+	done := make(chan error)
+	go func() {
+		done <- s.loadCache()
+	}()
+	<-repo.reading
+
+	// the reload has read a.example.com only
+	mustCreate(t, s, &types.Record{Domain: "b.example.com", Target: "192.0.2.2", Port: 9987})
+	if err = s.RemoveRecord("a.example.com"); err != nil {
+		t.Fatalf("RemoveRecord error: %v", err)
+	}
+	if err = s.SetRecord("c.example.com", "192.0.2.3", 9987); err != nil {
+		t.Fatalf("SetRecord error: %v", err)
+	}
+	close(repo.release)
+	if err = <-done; err != nil {
+		t.Fatalf("loadCache error: %v", err)
+	}
+
+	if finds := repo.finds.Load(); finds != 1 {
+		t.Fatalf("reload read the repository %d times, want once", finds)
+	}
+	mustResolve(t, s, "a.example.com", "")
+	mustResolve(t, s, "b.example.com", "192.0.2.2:9987")
+	mustResolve(t, s, "c.example.com", "192.0.2.3:9987")
+}
+
+func TestCacheWritesDuringReloads(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	const writers, perWriter = 4, 8
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	reloads := make(chan error, 1)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				close(reloads)
+				return
+			default:
+			}
+			if err := s.loadCache(); err != nil {
+				reloads <- err
+				close(reloads)
+				return
+			}
+		}
+	}()
+
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perWriter {
+				domain := fmt.Sprintf("w%d-%d.example.com", w, i)
+				if err := s.AddRecord(domain, "192.0.2.1", 9987); err != nil {
+					t.Errorf("AddRecord(%s) error: %v", domain, err)
+					return
+				}
+				// every other record is removed again
+				if i%2 == 1 {
+					if err := s.RemoveRecord(domain); err != nil {
+						t.Errorf("RemoveRecord(%s) error: %v", domain, err)
+						return
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	if err := <-reloads; err != nil {
+		t.Fatalf("loadCache error: %v", err)
+	}
+
+	for w := range writers {
+		for i := range perWriter {
+			domain := fmt.Sprintf("w%d-%d.example.com", w, i)
+			_, cached := s.cache.lookup(domain)
+			if want := i%2 == 0; cached != want {
+				t.Fatalf("%s cached = %v, want %v", domain, cached, want)
+			}
+		}
+	}
+}
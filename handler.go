@@ -1,13 +1,14 @@
 package tsdns
 
 import (
-	"fmt"
 	"net"
 	"strings"
 )
 
 // handleQuery processes incoming DNS queries
-// It looks up the domain in the cache and returns the corresponding record
+// Running maintenance windows are consulted first. Otherwise it looks up the domain in the
+// cache, falling back to pass-through and upstream lookups when configured, and returns
+// the corresponding record
 // If no record is found, returns "404"
 func (s *Server) handleQuery(conn net.Conn) {
 	defer conn.Close()
@@ -24,17 +25,14 @@ func (s *Server) handleQuery(conn net.Conn) {
 	}
 	s.logger.Debug("Query received: %s\n", domain)
 
-	// check cache
-	s.mu.RLock()
-	record, exists := s.cache[domain]
-	s.mu.RUnlock()
+	// maintenance takes precedence over the cache and the configured fallback sources
+	response, exists, redirected := s.maintenanceAnswer(domain)
+	if !redirected {
+		response, exists = s.resolve(domain)
+	}
 
 	// record found
 	if exists {
-		response := record.Target
-		if record.Port != 0 {
-			response = fmt.Sprintf("%s:%d", record.Target, record.Port)
-		}
 		conn.Write([]byte(response))
 		s.logger.Debug("Record found: %s -> %s\n", domain, response)
 		return
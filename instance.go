@@ -0,0 +1,135 @@
+package tsdns
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+// ListInstanceRecords returns the live records of an instance ordered by ID
+func (s *Server) ListInstanceRecords(instanceID int64) ([]*types.Record, error) {
+	return s.ListInstanceRecordsContext(context.Background(), instanceID)
+}
+
+// ListInstanceRecordsContext is like ListInstanceRecords but uses the deadline, tenant and actor of ctx
+func (s *Server) ListInstanceRecordsContext(ctx context.Context, instanceID int64) ([]*types.Record, error) {
+	return s.FindRecordsByInstanceContext(ctx, instanceID, false)
+}
+
+// DisableInstance disables all records of an instance, such as while its server is suspended
+// Disabled records answer like a miss, or with the target set by WithMaintenanceTarget.
+// The records are changed together or not at all, and the cache is updated immediately
+func (s *Server) DisableInstance(instanceID int64) error {
+	return s.DisableInstanceContext(context.Background(), instanceID)
+}
+
+// DisableInstanceContext is like DisableInstance but uses the deadline, tenant and actor of ctx
+func (s *Server) DisableInstanceContext(ctx context.Context, instanceID int64) error {
+	return s.setInstanceEnabled(ctx, instanceID, false)
+}
+
+// EnableInstance enables all records of an instance again
+// The records are changed together or not at all, and the cache is updated immediately
+func (s *Server) EnableInstance(instanceID int64) error {
+	return s.EnableInstanceContext(context.Background(), instanceID)
+}
+
+// EnableInstanceContext is like EnableInstance but uses the deadline, tenant and actor of ctx
+func (s *Server) EnableInstanceContext(ctx context.Context, instanceID int64) error {
+	return s.setInstanceEnabled(ctx, instanceID, true)
+}
+
+// setInstanceEnabled enables or disables all records of an instance
+func (s *Server) setInstanceEnabled(ctx context.Context, instanceID int64, enabled bool) error {
+	records, err := s.ListInstanceRecordsContext(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+
+	n, err := s.updateRecords(ctx, records, func(r *types.Record) bool {
+		if r.Enabled() == enabled {
+			return false
+		}
+		r.Disabled = !enabled
+		return true
+	})
+	if err != nil || n == 0 {
+		return err
+	}
+	if enabled {
+		s.logger.Info("Enabled %d records of instance %d\n", n, instanceID)
+	} else {
+		s.logger.Info("Disabled %d records of instance %d\n", n, instanceID)
+	}
+	return nil
+}
+
+// MoveInstance points all records of an instance to a new host and port, such as after
+// migrating its server. port 0 keeps the port of every record, aliases are left as they are.
+// The records are changed together or not at all, and the cache is updated immediately
+func (s *Server) MoveInstance(instanceID int64, target string, port int32) error {
+	return s.MoveInstanceContext(context.Background(), instanceID, target, port)
+}
+
+// MoveInstanceContext is like MoveInstance but uses the deadline, tenant and actor of ctx
+func (s *Server) MoveInstanceContext(ctx context.Context, instanceID int64, target string, port int32) error {
+	verr := &ValidationError{}
+	if reason := checkTarget(target); reason != "" {
+		verr.add("target", "%s", reason)
+	}
+	if port < 0 || port > maxPort {
+		verr.add("port", "%d is out of range 0-%d", port, maxPort)
+	}
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+
+	records, err := s.ListInstanceRecordsContext(ctx, instanceID)
+	if err != nil {
+		return err
+	}
+
+	n, err := s.updateRecords(ctx, records, func(r *types.Record) bool {
+		if r.Alias() || r.Target == target && (port == 0 || r.Port == port) {
+			return false
+		}
+		r.Target = target
+		if port != 0 {
+			r.Port = port
+		}
+		return true
+	})
+	if err != nil || n == 0 {
+		return err
+	}
+	s.logger.Info("Moved %d records of instance %d to %s\n", n, instanceID, types.Endpoint{Target: target, Port: port})
+	return nil
+}
+
+// TransferDomain hands all records of a domain, including scheduled ones, to another instance
+// The records are changed together or not at all, and the cache is updated immediately
+func (s *Server) TransferDomain(domain string, instanceID int64) error {
+	return s.TransferDomainContext(context.Background(), domain, instanceID)
+}
+
+// TransferDomainContext is like TransferDomain but uses the deadline, tenant and actor of ctx
+func (s *Server) TransferDomainContext(ctx context.Context, domain string, instanceID int64) error {
+	records := s.cache.domain(s.tenant(ctx), domain)
+	if len(records) == 0 {
+		return fmt.Errorf("%w: %s", types.ErrNotFound, domain)
+	}
+
+	n, err := s.updateRecords(ctx, records, func(r *types.Record) bool {
+		if r.InstanceID == instanceID {
+			return false
+		}
+		r.InstanceID = instanceID
+		return true
+	})
+	if err != nil || n == 0 {
+		return err
+	}
+	s.logger.Info("Transferred %s to instance %d\n", domain, instanceID)
+	return nil
+}
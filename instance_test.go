@@ -0,0 +1,131 @@
+package tsdns
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+// mustResolve checks the answer of the server for a domain, "" meaning a miss
+func mustResolve(t *testing.T, s *Server, domain, want string) {
+	t.Helper()
+	response, found := s.resolve(domain)
+	if !found {
+		response = ""
+	}
+	if response != want {
+		t.Fatalf("resolve(%s) = %q, want %q", domain, response, want)
+	}
+}
+
+func TestDisableInstance(t *testing.T) {
+	s := newTestServer(t, nil)
+	mustCreate(t, s,
+		&types.Record{InstanceID: 1, Domain: "a.example.com", Target: "192.0.2.1", Port: 9987},
+		&types.Record{InstanceID: 1, Domain: "b.example.com", Target: "192.0.2.1", Port: 9988},
+		&types.Record{InstanceID: 2, Domain: "c.example.com", Target: "192.0.2.2", Port: 9987},
+	)
+
+	if err := s.DisableInstance(1); err != nil {
+		t.Fatalf("DisableInstance error: %v", err)
+	}
+	mustResolve(t, s, "a.example.com", "")
+	mustResolve(t, s, "b.example.com", "")
+	mustResolve(t, s, "c.example.com", "192.0.2.2:9987")
+
+	records, err := s.ListInstanceRecords(1)
+	if err != nil {
+		t.Fatalf("ListInstanceRecords error: %v", err)
+	}
+	if len(records) != 2 || records[0].Domain != "a.example.com" || records[1].Domain != "b.example.com" {
+		t.Fatalf("ListInstanceRecords = %v, want the disabled records a and b in ID order", records)
+	}
+	for _, record := range records {
+		if record.Enabled() {
+			t.Fatalf("stored record %s is still enabled", record.Domain)
+		}
+	}
+
+	if err = s.EnableInstance(1); err != nil {
+		t.Fatalf("EnableInstance error: %v", err)
+	}
+	mustResolve(t, s, "a.example.com", "192.0.2.1:9987")
+	mustResolve(t, s, "b.example.com", "192.0.2.1:9988")
+}
+
+func TestMoveInstance(t *testing.T) {
+	s := newTestServer(t, nil)
+	mustCreate(t, s,
+		&types.Record{InstanceID: 1, Domain: "a.example.com", Target: "192.0.2.1", Port: 9987},
+		&types.Record{InstanceID: 1, Domain: "b.example.com", Target: "192.0.2.1", Port: 9988},
+		&types.Record{InstanceID: 1, Domain: "ts.example.com", AliasOf: "a.example.com"},
+	)
+
+	tests := []struct {
+		target string
+		port   int32
+	}{
+		{"192.0.2.9:9987", 0},
+		{"", 0},
+		{"new-host-.example.com", 0},
+		{"192.0.2.9", 70000},
+	}
+	for _, tt := range tests {
+		var verr *ValidationError
+		if err := s.MoveInstance(1, tt.target, tt.port); !errors.As(err, &verr) {
+			t.Fatalf("MoveInstance(%q, %d) error = %v, want a *ValidationError", tt.target, tt.port, err)
+		}
+	}
+
+	// nothing of the rejected moves was applied
+	records, err := s.FindRecordsByInstance(1, false)
+	if err != nil {
+		t.Fatalf("FindRecordsByInstance error: %v", err)
+	}
+	for _, record := range records {
+		if !record.Alias() && record.Target != "192.0.2.1" {
+			t.Fatalf("record %s was moved to %s by a rejected move", record.Domain, record.Target)
+		}
+	}
+	mustResolve(t, s, "a.example.com", "192.0.2.1:9987")
+
+	if err = s.MoveInstance(1, "new-host.example.com", 0); err != nil {
+		t.Fatalf("MoveInstance error: %v", err)
+	}
+	mustResolve(t, s, "a.example.com", "new-host.example.com:9987")
+	mustResolve(t, s, "b.example.com", "new-host.example.com:9988")
+	mustResolve(t, s, "ts.example.com", "new-host.example.com:9987")
+}
+
+func TestTransferDomain(t *testing.T) {
+	s := newTestServer(t, func(b *ServerBuilder) *ServerBuilder {
+		return b.WithPolicy(Policy{MaxDomains: 1})
+	})
+	mustCreate(t, s,
+		&types.Record{InstanceID: 1, Domain: "a.example.com", Target: "192.0.2.1", Port: 9987},
+		&types.Record{InstanceID: 2, Domain: "b.example.com", Target: "192.0.2.2", Port: 9987},
+	)
+
+	if err := s.TransferDomain("a.example.com", 2); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("TransferDomain to a full instance error = %v, want ErrQuotaExceeded", err)
+	}
+	if record, _ := s.cache.lookup("a.example.com"); record.InstanceID != 1 {
+		t.Fatalf("a.example.com belongs to instance %d after a rejected transfer, want 1", record.InstanceID)
+	}
+
+	if err := s.TransferDomain("a.example.com", 3); err != nil {
+		t.Fatalf("TransferDomain error: %v", err)
+	}
+	records, err := s.ListInstanceRecords(3)
+	if err != nil {
+		t.Fatalf("ListInstanceRecords error: %v", err)
+	}
+	if len(records) != 1 || records[0].Domain != "a.example.com" {
+		t.Fatalf("instance 3 holds %v, want a.example.com", records)
+	}
+
+	if err = s.TransferDomain("missing.example.com", 3); !errors.Is(err, types.ErrNotFound) {
+		t.Fatalf("TransferDomain of a missing domain error = %v, want ErrNotFound", err)
+	}
+}
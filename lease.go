@@ -0,0 +1,137 @@
+package tsdns
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+// defaultReapInterval is how often expired records are removed
+const defaultReapInterval = time.Minute
+
+// RenewRecord extends the lease of a record by ttl from now
+// Instances call it periodically as a heartbeat to keep their records alive
+func (s *Server) RenewRecord(domain string, ttl time.Duration) error {
+	return s.RenewRecordContext(context.Background(), domain, ttl)
+}
+
+// RenewRecordContext is like RenewRecord but uses the deadline, tenant and actor of ctx
+func (s *Server) RenewRecordContext(ctx context.Context, domain string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("lease ttl must be positive")
+	}
+
+	ctx, cancel := s.repoContext(ctx)
+	defer cancel()
+
+	expiresAt := time.Now().Add(ttl)
+	if err := s.repository.Renew(ctx, domain, expiresAt); err != nil {
+		return err
+	}
+
+	tenant := s.tenant(ctx)
+	s.cache.modify(func(c *recordCache) {
+		c.update(func(r *types.Record) bool {
+			return r.Tenant == tenant && r.Domain == domain
+		}, func(r *types.Record) {
+			r.ExpiresAt = &expiresAt
+		})
+	})
+	return nil
+}
+
+// RenewInstance extends the lease of all records of an instance by ttl from now
+func (s *Server) RenewInstance(instanceID int64, ttl time.Duration) error {
+	return s.RenewInstanceContext(context.Background(), instanceID, ttl)
+}
+
+// RenewInstanceContext is like RenewInstance but uses the deadline, tenant and actor of ctx
+func (s *Server) RenewInstanceContext(ctx context.Context, instanceID int64, ttl time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("lease ttl must be positive")
+	}
+
+	ctx, cancel := s.repoContext(ctx)
+	defer cancel()
+
+	expiresAt := time.Now().Add(ttl)
+	if err := s.repository.RenewByInstanceID(ctx, instanceID, expiresAt); err != nil {
+		return err
+	}
+
+	tenant := s.tenant(ctx)
+	s.cache.modify(func(c *recordCache) {
+		c.update(func(r *types.Record) bool {
+			return r.Tenant == tenant && r.InstanceID == instanceID
+		}, func(r *types.Record) {
+			r.ExpiresAt = &expiresAt
+		})
+	})
+	return nil
+}
+
+// reapExpired removes records of the served tenants whose lease has ended from the repository and the cache
+// Instances left without records lose their reserved ports, and maintenance windows that ended are removed as well
+func (s *Server) reapExpired() error {
+	now := time.Now()
+	var count int64
+	for _, tenant := range s.tenants {
+		ctx, cancel := s.repoContext(types.WithTenant(types.WithActor(s.ctx, reaperActor), tenant))
+		n, err := s.repository.DeleteExpired(ctx, now)
+		if err == nil && n > 0 {
+			err = s.releaseExpiredPorts(ctx, s.cache.expiredInstances(tenant, now))
+		}
+		cancel()
+		if err != nil {
+			return err
+		}
+		count += n
+	}
+
+	s.cache.modify(func(c *recordCache) {
+		c.remove(func(r *types.Record) bool {
+			return r.Expired(now)
+		})
+	})
+	if count > 0 {
+		s.logger.Info("Removed %d expired records\n", count)
+	}
+	return s.reapMaintenance(now)
+}
+
+// releaseExpiredPorts releases the ports of instances whose records all expired
+// Instances still holding a record keep their ports
+func (s *Server) releaseExpiredPorts(ctx context.Context, instanceIDs []int64) error {
+	for _, instanceID := range instanceIDs {
+		records, err := s.repository.FindByInstanceID(ctx, instanceID, false)
+		if err != nil {
+			return err
+		}
+		if len(records) > 0 {
+			continue
+		}
+		if err = s.releasePorts(ctx, instanceID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reaper periodically removes expired records and ended maintenance windows
+func (s *Server) reaper() {
+	ticker := time.NewTicker(s.reapInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.reapExpired(); err != nil {
+				s.logger.Error("Reap expired records error: %v\n", err)
+			}
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package tsdns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+// upstreamTimeout bounds a single query to the upstream TSDNS server
+const upstreamTimeout = 3 * time.Second
+
+// resolve returns the response for a domain
+//
+// The record cache is consulted first, following aliases to the record they point to.
+// On a miss the answer cache is checked, then the slow sources (pass-through repository
+// reads and the upstream server) and their result is stored in the answer cache
+func (s *Server) resolve(domain string) (string, bool) {
+	if record, exists := s.cache.lookup(domain); exists {
+		record, err := followAliases(record, s.cache.finder(record.Tenant))
+		if err != nil {
+			s.logger.Warn("Lookup of %s failed: %v\n", domain, err)
+			return "", false
+		}
+		if record != nil {
+			return s.answer(record)
+		}
+		// the record an alias points to is not cached, the slow sources may know it
+	}
+
+	if !s.passThrough && s.upstream == "" {
+		return "", false
+	}
+
+	if s.answers != nil {
+		if response, found, ok := s.answers.get(domain); ok {
+			return response, found
+		}
+	}
+
+	response, found, err := s.resolveSlow(domain)
+	if err != nil {
+		// errors are not cached so the next query tries again
+		s.logger.Warn("Lookup of %s failed: %v\n", domain, err)
+		return "", false
+	}
+
+	if s.answers != nil {
+		ttl := s.answerTTL
+		if !found {
+			ttl = s.negativeTTL
+		}
+		s.answers.set(domain, response, found, ttl)
+	}
+	return response, found
+}
+
+// resolveSlow looks up a domain in the repository and the upstream server
+func (s *Server) resolveSlow(domain string) (string, bool, error) {
+	if s.passThrough {
+		ctx, cancel := s.repoContext(s.ctx)
+		record, err := s.findServedRecord(ctx, domain)
+		if err == nil && record != nil {
+			// aliases resolve within the tenant of the record
+			tenantCtx := types.WithTenant(ctx, record.Tenant)
+			record, err = followAliases(record, func(domain string) (*types.Record, error) {
+				return s.findRecord(tenantCtx, domain)
+			})
+		}
+		cancel()
+		switch {
+		case err != nil:
+			return "", false, err
+		case record != nil:
+			response, found := s.answer(record)
+			return response, found, nil
+		case s.upstream == "":
+			return "", false, nil
+		}
+	}
+
+	return s.queryUpstream(domain)
+}
+
+// findServedRecord reads the record answering for a domain in the first served tenant holding one, or nil
+func (s *Server) findServedRecord(ctx context.Context, domain string) (*types.Record, error) {
+	for _, tenant := range s.tenants {
+		record, err := s.findRecord(types.WithTenant(ctx, tenant), domain)
+		if err != nil || record != nil {
+			return record, err
+		}
+	}
+	return nil, nil
+}
+
+// findRecord reads the record answering for a domain in the tenant of ctx from the repository, or nil
+func (s *Server) findRecord(ctx context.Context, domain string) (*types.Record, error) {
+	record, err := s.repository.FindByDomain(ctx, domain)
+	if errors.Is(err, types.ErrNotFound) {
+		return nil, nil
+	}
+	return record, err
+}
+
+// queryUpstream forwards a query to the upstream TSDNS server
+func (s *Server) queryUpstream(domain string) (string, bool, error) {
+	conn, err := net.DialTimeout("tcp", s.upstream, upstreamTimeout)
+	if err != nil {
+		return "", false, fmt.Errorf("dial upstream: %v", err)
+	}
+	defer conn.Close()
+
+	if err = conn.SetDeadline(time.Now().Add(upstreamTimeout)); err != nil {
+		return "", false, err
+	}
+	if _, err = conn.Write([]byte(domain)); err != nil {
+		return "", false, fmt.Errorf("write upstream: %v", err)
+	}
+
+	buf := make([]byte, 512)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return "", false, fmt.Errorf("read upstream: %v", err)
+	}
+
+	response := strings.TrimSpace(string(buf[:n]))
+	if response == "" || response == "404" {
+		return "", false, nil
+	}
+	return response, true, nil
+}
+
+// answer returns the response for the record answering a domain
+// A disabled record answers with the maintenance target, or as a miss if there is none
+func (s *Server) answer(record *types.Record) (string, bool) {
+	if record.Enabled() {
+		return formatRecord(record), true
+	}
+	if s.maintenance.Target == "" {
+		return "", false
+	}
+	return s.maintenance.String(), true
+}
+
+// formatRecord returns the TSDNS response for a record
+func formatRecord(record *types.Record) string {
+	if record.Port != 0 {
+		return fmt.Sprintf("%s:%d", record.Target, record.Port)
+	}
+	return record.Target
+}
@@ -0,0 +1,363 @@
+package tsdns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+// SetMaintenance redirects the queries of a scope to target until ClearMaintenance is called
+//
+// An empty target redirects to the target set with WithMaintenanceTarget. Maintenance windows
+// take precedence over every record, and when several apply the most specific scope wins:
+// domain before instance before global. An instance scope covers the domains answered by
+// cached records of the instance. The running windows of the scope are replaced in the same
+// repository operation. It returns types.ErrNotSupported if the repository cannot persist
+// maintenance windows
+func (s *Server) SetMaintenance(scope types.MaintenanceScope, target types.Endpoint) error {
+	return s.SetMaintenanceContext(context.Background(), scope, target)
+}
+
+// SetMaintenanceContext is like SetMaintenance but uses the deadline, tenant and actor of ctx
+func (s *Server) SetMaintenanceContext(ctx context.Context, scope types.MaintenanceScope, target types.Endpoint) error {
+	window := &types.Maintenance{Scope: scope, Target: target}
+	if err := s.validateMaintenance(window); err != nil {
+		return err
+	}
+	repo, ok := s.repository.(types.MaintenanceRepository)
+	if !ok {
+		return types.ErrNotSupported
+	}
+
+	ctx, cancel := s.repoContext(ctx)
+	defer cancel()
+
+	replaced, err := repo.ReplaceMaintenance(ctx, window, time.Now())
+	if err != nil {
+		return err
+	}
+
+	stored := *window
+	s.modifyWindows(func(windows []*types.Maintenance) []*types.Maintenance {
+		windows = slices.DeleteFunc(windows, func(existing *types.Maintenance) bool {
+			return existing.Tenant == stored.Tenant && slices.Contains(replaced, existing.ID)
+		})
+		if slices.Contains(s.tenants, stored.Tenant) {
+			windows = putWindow(windows, &stored)
+		}
+		return windows
+	})
+	s.logger.Info("Started maintenance of %s\n", window.Scope)
+	return nil
+}
+
+// ScheduleMaintenance redirects the queries of a scope to target between from and until
+// A zero until leaves the window open until it is cleared. It returns the ID of the window
+func (s *Server) ScheduleMaintenance(scope types.MaintenanceScope, target types.Endpoint, from, until time.Time) (int64, error) {
+	return s.ScheduleMaintenanceContext(context.Background(), scope, target, from, until)
+}
+
+// ScheduleMaintenanceContext is like ScheduleMaintenance but uses the deadline, tenant and actor of ctx
+func (s *Server) ScheduleMaintenanceContext(ctx context.Context, scope types.MaintenanceScope, target types.Endpoint, from, until time.Time) (int64, error) {
+	window := &types.Maintenance{Scope: scope, Target: target, From: &from}
+	if !until.IsZero() {
+		window.Until = &until
+	}
+	return s.createMaintenance(ctx, window)
+}
+
+// ClearMaintenance ends the running maintenance windows of a scope
+// Windows scheduled to start later are kept, see CancelMaintenance
+func (s *Server) ClearMaintenance(scope types.MaintenanceScope) error {
+	return s.ClearMaintenanceContext(context.Background(), scope)
+}
+
+// ClearMaintenanceContext is like ClearMaintenance but uses the deadline, tenant and actor of ctx
+func (s *Server) ClearMaintenanceContext(ctx context.Context, scope types.MaintenanceScope) error {
+	windows, err := s.ListMaintenanceContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, window := range windows {
+		if window.Scope == scope && window.ActiveAt(now) {
+			if err = s.CancelMaintenanceContext(ctx, window.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// CancelMaintenance removes a running or scheduled maintenance window
+func (s *Server) CancelMaintenance(id int64) error {
+	return s.CancelMaintenanceContext(context.Background(), id)
+}
+
+// CancelMaintenanceContext is like CancelMaintenance but uses the deadline, tenant and actor of ctx
+func (s *Server) CancelMaintenanceContext(ctx context.Context, id int64) error {
+	if err := s.deleteMaintenance(ctx, id); err != nil {
+		return err
+	}
+	s.logger.Info("Cancelled maintenance window %d\n", id)
+	return nil
+}
+
+// ListMaintenance returns the maintenance windows ordered by ID
+// Ended windows are included until the reaper removes them
+func (s *Server) ListMaintenance() ([]*types.Maintenance, error) {
+	return s.ListMaintenanceContext(context.Background())
+}
+
+// ListMaintenanceContext is like ListMaintenance but uses the deadline, tenant and actor of ctx
+func (s *Server) ListMaintenanceContext(ctx context.Context) ([]*types.Maintenance, error) {
+	repo, ok := s.repository.(types.MaintenanceRepository)
+	if !ok {
+		return nil, types.ErrNotSupported
+	}
+
+	ctx, cancel := s.repoContext(ctx)
+	defer cancel()
+
+	return repo.FindMaintenance(ctx)
+}
+
+// createMaintenance validates and stores a maintenance window and returns its ID
+func (s *Server) createMaintenance(ctx context.Context, window *types.Maintenance) (int64, error) {
+	if err := s.validateMaintenance(window); err != nil {
+		return 0, err
+	}
+	repo, ok := s.repository.(types.MaintenanceRepository)
+	if !ok {
+		return 0, types.ErrNotSupported
+	}
+
+	ctx, cancel := s.repoContext(ctx)
+	defer cancel()
+
+	if err := repo.CreateMaintenance(ctx, window); err != nil {
+		return 0, err
+	}
+
+	if slices.Contains(s.tenants, window.Tenant) {
+		stored := *window
+		s.modifyWindows(func(windows []*types.Maintenance) []*types.Maintenance {
+			return putWindow(windows, &stored)
+		})
+	}
+	s.logger.Info("Scheduled maintenance of %s\n", window.Scope)
+	return window.ID, nil
+}
+
+// deleteMaintenance removes a maintenance window from the repository and from memory
+// A window another server removed already is dropped from memory as well
+func (s *Server) deleteMaintenance(ctx context.Context, id int64) error {
+	repo, ok := s.repository.(types.MaintenanceRepository)
+	if !ok {
+		return types.ErrNotSupported
+	}
+
+	ctx, cancel := s.repoContext(ctx)
+	defer cancel()
+
+	err := repo.DeleteMaintenance(ctx, id)
+	if err != nil && !errors.Is(err, types.ErrNotFound) {
+		return err
+	}
+
+	tenant := s.tenant(ctx)
+	s.modifyWindows(func(windows []*types.Maintenance) []*types.Maintenance {
+		return slices.DeleteFunc(windows, func(window *types.Maintenance) bool {
+			return window.Tenant == tenant && window.ID == id
+		})
+	})
+	return err
+}
+
+// validateMaintenance checks the scope and target of a maintenance window
+// It returns an error matching types.ErrInvalidMaintenance
+func (s *Server) validateMaintenance(window *types.Maintenance) error {
+	if err := window.Validate(); err != nil {
+		return err
+	}
+	if window.Scope.Kind == types.MaintenanceDomain {
+		if reason := checkHostname(window.Scope.Domain); reason != "" {
+			return fmt.Errorf("%w: domain %s", types.ErrInvalidMaintenance, reason)
+		}
+	}
+	switch {
+	case window.Target.Target != "":
+		if reason := checkTarget(window.Target.Target); reason != "" {
+			return fmt.Errorf("%w: target %s", types.ErrInvalidMaintenance, reason)
+		}
+	case window.Target.Port != 0:
+		return fmt.Errorf("%w: port without target", types.ErrInvalidMaintenance)
+	case s.maintenance.Target == "":
+		return fmt.Errorf("%w: no target and no default set with WithMaintenanceTarget", types.ErrInvalidMaintenance)
+	}
+	return nil
+}
+
+// maintenanceAnswer returns the response for a domain redirected by a running maintenance window
+// redirected is false if no window applies. A window without a target answers with the target
+// set by WithMaintenanceTarget, or as a miss if there is none
+func (s *Server) maintenanceAnswer(domain string) (response string, found, redirected bool) {
+	windows := s.cache.maintenance()
+	if len(windows) == 0 {
+		return "", false, false
+	}
+
+	now := time.Now()
+	var answering *answeringChain
+	var match *types.Maintenance
+	for _, window := range windows {
+		if !window.ActiveAt(now) || match != nil && maintenanceRank(window) < maintenanceRank(match) {
+			continue
+		}
+		if answering == nil {
+			answering = s.answering(domain)
+		}
+		if !answering.covers(window) {
+			continue
+		}
+		// among windows of the same scope the latest wins
+		match = window
+	}
+	if match == nil {
+		return "", false, false
+	}
+
+	target := match.Target
+	if target.Target == "" {
+		target = s.maintenance
+	}
+	if target.Target == "" {
+		return "", false, true
+	}
+	return target.String(), true, true
+}
+
+// maintenanceRank orders maintenance windows by how specific their scope is
+func maintenanceRank(window *types.Maintenance) int {
+	switch window.Scope.Kind {
+	case types.MaintenanceDomain:
+		return 2
+	case types.MaintenanceInstance:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// answeringChain is what answers for a queried domain, as far as maintenance windows are concerned
+type answeringChain struct {
+	// tenant holds the answering record, or is the default tenant if there is none
+	tenant string
+	// domains are the queried domain and the domains of the aliases followed from it
+	domains []string
+	// record is the canonical record answering, or nil
+	record *types.Record
+}
+
+// answering follows the cached aliases from a domain to the record answering for it
+// A loop or a missing record stops at the last domain reached
+func (s *Server) answering(domain string) *answeringChain {
+	chain := &answeringChain{tenant: s.tenants[0], domains: []string{domain}}
+	record, exists := s.cache.lookup(domain)
+	if !exists {
+		return chain
+	}
+
+	chain.tenant = record.Tenant
+	find := s.cache.finder(record.Tenant)
+	for record.Alias() && record.Enabled() && len(chain.domains) <= maxAliasDepth {
+		if slices.Contains(chain.domains, record.AliasOf) {
+			break
+		}
+		chain.domains = append(chain.domains, record.AliasOf)
+		next, _ := find(record.AliasOf)
+		if next == nil {
+			break
+		}
+		record = next
+	}
+	chain.record = record
+	return chain
+}
+
+// covers reports whether a maintenance window applies to the chain
+// Windows only apply to the domains of their own tenant
+func (c *answeringChain) covers(window *types.Maintenance) bool {
+	if window.Tenant != c.tenant {
+		return false
+	}
+	switch window.Scope.Kind {
+	case types.MaintenanceDomain:
+		return slices.Contains(c.domains, window.Scope.Domain)
+	case types.MaintenanceInstance:
+		return c.record != nil && c.record.InstanceID == window.Scope.InstanceID
+	default:
+		return true
+	}
+}
+
+// modifyWindows replaces the cached maintenance windows with the result of fn on a copy of them
+// fn may run again on the windows of a reload, see recordCache.modify
+func (s *Server) modifyWindows(fn func(windows []*types.Maintenance) []*types.Maintenance) {
+	s.cache.modify(func(c *recordCache) {
+		c.windows = fn(slices.Clone(c.windows))
+	})
+}
+
+// putWindow adds a maintenance window, replacing the window of the same tenant and ID
+func putWindow(windows []*types.Maintenance, window *types.Maintenance) []*types.Maintenance {
+	windows = slices.DeleteFunc(windows, func(existing *types.Maintenance) bool {
+		return existing.Tenant == window.Tenant && existing.ID == window.ID
+	})
+	return append(windows, window)
+}
+
+// findMaintenance reads the maintenance windows of all served tenants from the repository
+// Repositories that cannot persist maintenance windows have none
+func (s *Server) findMaintenance() ([]*types.Maintenance, error) {
+	repo, ok := s.repository.(types.MaintenanceRepository)
+	if !ok {
+		return nil, nil
+	}
+
+	var windows []*types.Maintenance
+	for _, tenant := range s.tenants {
+		ctx, cancel := s.repoContext(types.WithTenant(s.ctx, tenant))
+		tenantWindows, err := repo.FindMaintenance(ctx)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, tenantWindows...)
+	}
+	return windows, nil
+}
+
+// reapMaintenance removes the maintenance windows of the served tenants that ended at or before now
+func (s *Server) reapMaintenance(now time.Time) error {
+	var count int
+	for _, window := range s.cache.maintenance() {
+		if !window.Ended(now) {
+			continue
+		}
+		err := s.deleteMaintenance(types.WithTenant(s.ctx, window.Tenant), window.ID)
+		if err != nil && !errors.Is(err, types.ErrNotFound) {
+			return err
+		}
+		count++
+	}
+	if count > 0 {
+		s.logger.Info("Removed %d ended maintenance windows\n", count)
+	}
+	return nil
+}
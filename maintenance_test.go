@@ -0,0 +1,198 @@
+package tsdns
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+func TestMaintenanceAnswer(t *testing.T) {
+	type window struct {
+		tenant string
+		scope  types.MaintenanceScope
+		target string
+		// from and until are relative to now, zero leaves the window open on that side
+		from, until time.Duration
+	}
+
+	// a and c belong to instance 1, b is an alias of a, d belongs to instance 2,
+	// e belongs to instance 1 of the tenant other
+	tests := []struct {
+		name    string
+		windows []window
+		// want maps queried domains to the maintenance target answering, "" if none
+		want map[string]string
+	}{
+		{
+			name: "no window",
+			want: map[string]string{"a.example.com": "", "d.example.com": ""},
+		},
+		{
+			name:    "global",
+			windows: []window{{scope: types.GlobalScope(), target: "global.example.com"}},
+			want:    map[string]string{"a.example.com": "global.example.com", "unknown.example.com": "global.example.com", "e.example.com": ""},
+		},
+		{
+			name: "instance before global",
+			windows: []window{
+				{scope: types.InstanceScope(1), target: "instance.example.com"},
+				{scope: types.GlobalScope(), target: "global.example.com"},
+			},
+			want: map[string]string{"a.example.com": "instance.example.com", "b.example.com": "instance.example.com", "d.example.com": "global.example.com"},
+		},
+		{
+			name: "domain before instance",
+			windows: []window{
+				{scope: types.GlobalScope(), target: "global.example.com"},
+				{scope: types.DomainScope("a.example.com"), target: "domain.example.com"},
+				{scope: types.InstanceScope(1), target: "instance.example.com"},
+			},
+			want: map[string]string{"a.example.com": "domain.example.com", "c.example.com": "instance.example.com", "d.example.com": "global.example.com"},
+		},
+		{
+			name:    "domain window covers aliases",
+			windows: []window{{scope: types.DomainScope("a.example.com"), target: "domain.example.com"}},
+			want:    map[string]string{"b.example.com": "domain.example.com", "c.example.com": ""},
+		},
+		{
+			name:    "window on an alias leaves the canonical domain",
+			windows: []window{{scope: types.DomainScope("b.example.com"), target: "domain.example.com"}},
+			want:    map[string]string{"a.example.com": "", "b.example.com": "domain.example.com"},
+		},
+		{
+			name: "latest window of a scope wins",
+			windows: []window{
+				{scope: types.InstanceScope(1), target: "first.example.com"},
+				{scope: types.InstanceScope(1), target: "second.example.com"},
+			},
+			want: map[string]string{"a.example.com": "second.example.com"},
+		},
+		{
+			name: "windows apply to their own tenant",
+			windows: []window{
+				{tenant: "other", scope: types.InstanceScope(1), target: "other.example.com"},
+				{tenant: "other", scope: types.DomainScope("a.example.com"), target: "other.example.com"},
+			},
+			want: map[string]string{"a.example.com": "", "e.example.com": "other.example.com"},
+		},
+		{
+			name: "ended and future windows",
+			windows: []window{
+				{scope: types.DomainScope("a.example.com"), target: "ended.example.com", from: -2 * time.Hour, until: -time.Hour},
+				{scope: types.InstanceScope(1), target: "future.example.com", from: time.Hour},
+				{scope: types.GlobalScope(), target: "global.example.com", from: -time.Hour, until: time.Hour},
+			},
+			want: map[string]string{"a.example.com": "global.example.com"},
+		},
+		{
+			name:    "default target",
+			windows: []window{{scope: types.InstanceScope(2)}},
+			want:    map[string]string{"d.example.com": "soon.example.com:9987", "a.example.com": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, func(b *ServerBuilder) *ServerBuilder {
+				return b.WithTenants("", "other").WithMaintenanceTarget("soon.example.com", 9987)
+			})
+			ctx := context.Background()
+			mustCreate(t, s,
+				&types.Record{InstanceID: 1, Domain: "a.example.com", Target: "192.0.2.1"},
+				&types.Record{InstanceID: 1, Domain: "c.example.com", Target: "192.0.2.1"},
+				&types.Record{InstanceID: 2, Domain: "d.example.com", Target: "192.0.2.2"},
+			)
+			if err := s.AddAliasContext(ctx, "b.example.com", "a.example.com"); err != nil {
+				t.Fatalf("AddAlias error: %v", err)
+			}
+			mustCreate(t, s, &types.Record{InstanceID: 1, Domain: "e.example.com", Target: "192.0.2.3"})
+			if err := s.CreateRecordContext(types.WithTenant(ctx, "other"), &types.Record{InstanceID: 1, Domain: "e.example.com", Target: "192.0.2.3"}); err != nil {
+				t.Fatalf("CreateRecord in other tenant error: %v", err)
+			}
+			if err := s.RemoveRecordContext(ctx, "e.example.com"); err != nil {
+				t.Fatalf("RemoveRecord error: %v", err)
+			}
+
+			now := time.Now()
+			for _, w := range tt.windows {
+				var until time.Time
+				if w.until != 0 {
+					until = now.Add(w.until)
+				}
+				_, err := s.ScheduleMaintenanceContext(types.WithTenant(ctx, w.tenant), w.scope, types.Endpoint{Target: w.target}, now.Add(w.from), until)
+				if err != nil {
+					t.Fatalf("ScheduleMaintenance(%s) error: %v", w.scope, err)
+				}
+			}
+
+			for domain, want := range tt.want {
+				response, found, redirected := s.maintenanceAnswer(domain)
+				switch {
+				case want == "" && redirected:
+					t.Errorf("%s redirected to %q, want no maintenance", domain, response)
+				case want != "" && (!found || response != want):
+					t.Errorf("%s answered %q, found %v, redirected %v, want %q", domain, response, found, redirected, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSetMaintenanceReplaces(t *testing.T) {
+	s := newTestServer(t, nil)
+	ctx := context.Background()
+
+	scope := types.InstanceScope(1)
+	for _, target := range []string{"first.example.com", "second.example.com"} {
+		if err := s.SetMaintenanceContext(ctx, scope, types.Endpoint{Target: target}); err != nil {
+			t.Fatalf("SetMaintenance(%s) error: %v", target, err)
+		}
+	}
+	later, err := s.ScheduleMaintenanceContext(ctx, scope, types.Endpoint{Target: "later.example.com"}, time.Now().Add(time.Hour), time.Time{})
+	if err != nil {
+		t.Fatalf("ScheduleMaintenance error: %v", err)
+	}
+	if err = s.SetMaintenanceContext(ctx, scope, types.Endpoint{Target: "third.example.com"}); err != nil {
+		t.Fatalf("SetMaintenance error: %v", err)
+	}
+
+	stored, err := s.ListMaintenanceContext(ctx)
+	if err != nil {
+		t.Fatalf("ListMaintenance error: %v", err)
+	}
+	if len(stored) != 2 || stored[0].ID != later || stored[1].Target.Target != "third.example.com" {
+		t.Fatalf("ListMaintenance = %d windows, want the scheduled and the last set one", len(stored))
+	}
+	if windows := s.cache.maintenance(); len(windows) != 2 {
+		t.Fatalf("server holds %d windows, want 2", len(windows))
+	}
+}
+
+func TestMaintenanceSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.bin")
+	s := newTestServer(t, func(b *ServerBuilder) *ServerBuilder {
+		return b.WithSnapshot(path)
+	})
+	ctx := context.Background()
+	mustCreate(t, s, &types.Record{InstanceID: 1, Domain: "a.example.com", Target: "192.0.2.1"})
+	if err := s.SetMaintenanceContext(ctx, types.InstanceScope(1), types.Endpoint{Target: "soon.example.com"}); err != nil {
+		t.Fatalf("SetMaintenance error: %v", err)
+	}
+	if err := s.loadCache(); err != nil {
+		t.Fatalf("loadCache error: %v", err)
+	}
+
+	// a server starting degraded serves the windows along with the records
+	degraded := newTestServer(t, func(b *ServerBuilder) *ServerBuilder {
+		return b.WithSnapshot(path)
+	})
+	if err := degraded.loadSnapshot(); err != nil {
+		t.Fatalf("loadSnapshot error: %v", err)
+	}
+	if response, found, _ := degraded.maintenanceAnswer("a.example.com"); !found || response != "soon.example.com" {
+		t.Fatalf("a.example.com answered %q from the snapshot, want soon.example.com", response)
+	}
+}
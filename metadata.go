@@ -0,0 +1,42 @@
+package tsdns
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+// SetRecordEnabled enables or disables all records of a domain, including scheduled ones
+//
+// A disabled record is kept but answers like a miss, or with the target set by
+// WithMaintenanceTarget. The records are changed together or not at all, and a
+// record changed concurrently fails the call with types.ErrVersionConflict
+func (s *Server) SetRecordEnabled(domain string, enabled bool) error {
+	return s.SetRecordEnabledContext(context.Background(), domain, enabled)
+}
+
+// SetRecordEnabledContext is like SetRecordEnabled but uses the deadline, tenant and actor of ctx
+func (s *Server) SetRecordEnabledContext(ctx context.Context, domain string, enabled bool) error {
+	records := s.cache.domain(s.tenant(ctx), domain)
+	if len(records) == 0 {
+		return fmt.Errorf("%w: %s", types.ErrNotFound, domain)
+	}
+
+	n, err := s.updateRecords(ctx, records, func(r *types.Record) bool {
+		if r.Enabled() == enabled {
+			return false
+		}
+		r.Disabled = !enabled
+		return true
+	})
+	if err != nil || n == 0 {
+		return err
+	}
+	if enabled {
+		s.logger.Info("Enabled %s\n", domain)
+	} else {
+		s.logger.Info("Disabled %s\n", domain)
+	}
+	return nil
+}
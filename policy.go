@@ -0,0 +1,280 @@
+package tsdns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+var (
+	// ErrQuotaExceeded is returned when a change would give an instance more domains than its quota
+	ErrQuotaExceeded = errors.New("instance domain quota exceeded")
+
+	// ErrDomainReserved is returned when a change stores a domain matching a reserved or blocked pattern
+	ErrDomainReserved = errors.New("domain reserved")
+)
+
+// Policy restricts the domains instances may claim
+//
+// Patterns use the syntax of path.Match and are matched case-insensitively, "admin.*"
+// matches admin.example.com and "*teamspeak*" any domain containing teamspeak
+type Policy struct {
+	// MaxDomains is how many domains an instance may hold, aliases included, 0 means unlimited
+	MaxDomains int
+	// InstanceMaxDomains overrides MaxDomains for single instances
+	InstanceMaxDomains map[int64]int
+	// Reserved lists domain patterns only records without an instance may use
+	Reserved []string
+	// Blocked lists domain patterns no record may use
+	Blocked []string
+}
+
+// validate checks the quotas and patterns of the policy
+func (p *Policy) validate() error {
+	if p.MaxDomains < 0 {
+		return fmt.Errorf("policy domain quota must not be negative")
+	}
+	for id, limit := range p.InstanceMaxDomains {
+		if limit < 0 {
+			return fmt.Errorf("policy domain quota of instance %d must not be negative", id)
+		}
+	}
+	for _, pattern := range append(append([]string(nil), p.Reserved...), p.Blocked...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("policy pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// limit returns the domain quota of an instance, 0 means unlimited
+func (p *Policy) limit(instanceID int64) int {
+	if limit, ok := p.InstanceMaxDomains[instanceID]; ok {
+		return limit
+	}
+	return p.MaxDomains
+}
+
+// quotas reports whether any instance has a domain quota
+func (p *Policy) quotas() bool {
+	if p.MaxDomains > 0 {
+		return true
+	}
+	for _, limit := range p.InstanceMaxDomains {
+		if limit > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// QuotaError is returned when a change would exceed the domain quota of an instance
+// It matches ErrQuotaExceeded
+type QuotaError struct {
+	InstanceID int64
+	Limit      int
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%v: instance %d may hold %d domains", ErrQuotaExceeded, e.InstanceID, e.Limit)
+}
+
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// ReservedDomainError is returned when a change stores a domain the policy reserves or blocks
+// It matches ErrDomainReserved
+type ReservedDomainError struct {
+	Domain  string
+	Pattern string
+	// Blocked is set if the pattern is blocked for every record rather than reserved
+	Blocked bool
+}
+
+func (e *ReservedDomainError) Error() string {
+	kind := "reserved"
+	if e.Blocked {
+		kind = "blocked"
+	}
+	return fmt.Sprintf("%v: %s matches %s pattern %q", ErrDomainReserved, e.Domain, kind, e.Pattern)
+}
+
+func (e *ReservedDomainError) Unwrap() error {
+	return ErrDomainReserved
+}
+
+// enforcePolicy checks changes against the policy before they are applied
+//
+// With quotas configured it serializes the writes of the server so two changes cannot
+// both take the last free slot of an instance, the caller must call unlock once the
+// changes are applied. Writes of other servers sharing the repository are not covered
+func (s *Server) enforcePolicy(ctx context.Context, changes []types.Change) (unlock func(), err error) {
+	if s.policy == nil {
+		return func() {}, nil
+	}
+
+	tenant := s.tenant(ctx)
+	for i, change := range changes {
+		if err = s.checkDomain(tenant, change); err != nil {
+			return nil, changeError(changes, i, err)
+		}
+	}
+	if !s.policy.quotas() {
+		return func() {}, nil
+	}
+
+	s.policyMu.Lock()
+	if err = s.checkQuotas(ctx, changes); err != nil {
+		s.policyMu.Unlock()
+		return nil, err
+	}
+	return s.policyMu.Unlock, nil
+}
+
+// checkDomain rejects a change storing a reserved or blocked domain
+// Updates of a record keeping its domain pass, so records stored before the patterns
+// were added can still be disabled, moved or transferred
+func (s *Server) checkDomain(tenant string, change types.Change) error {
+	if change.Record == nil || !storesRecord(change.Op) {
+		return nil
+	}
+	if change.Op != types.ChangeCreate {
+		for _, stored := range s.cache.domain(tenant, change.Record.Domain) {
+			if sameRecord(stored, change.Record) {
+				return nil
+			}
+		}
+	}
+
+	domain := strings.ToLower(change.Record.Domain)
+	if pattern, ok := matchPattern(s.policy.Blocked, domain); ok {
+		return &ReservedDomainError{Domain: change.Record.Domain, Pattern: pattern, Blocked: true}
+	}
+	if change.Record.InstanceID == 0 {
+		return nil
+	}
+	if pattern, ok := matchPattern(s.policy.Reserved, domain); ok {
+		return &ReservedDomainError{Domain: change.Record.Domain, Pattern: pattern}
+	}
+	return nil
+}
+
+// matchPattern returns the first pattern matching the lowercase domain
+func matchPattern(patterns []string, domain string) (string, bool) {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), domain); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// checkQuotas rejects changes that would leave an instance with more domains than its quota
+//
+// The changes are replayed in order on the records the instances gaining a domain hold in the
+// repository. A record updated to another instance frees its domain in the old one unless
+// another record of the old instance holds it. An instance already over its quota, such as
+// after lowering it, may keep its domains
+func (s *Server) checkQuotas(ctx context.Context, changes []types.Change) error {
+	// domains of the instances with a quota, counting the records holding each
+	held := make(map[int64]map[string]int)
+	start := make(map[int64]int)
+	// records of those instances, including the ones stored by the batch
+	var records []*types.Record
+	for _, change := range changes {
+		if change.Record == nil || !storesRecord(change.Op) {
+			continue
+		}
+		id := change.Record.InstanceID
+		if _, ok := held[id]; ok || id == 0 || s.policy.limit(id) == 0 {
+			continue
+		}
+		stored, err := s.repository.FindByInstanceID(ctx, id, false)
+		if err != nil {
+			return err
+		}
+		set := make(map[string]int, len(stored))
+		for _, record := range stored {
+			set[record.Domain]++
+		}
+		held[id], start[id] = set, len(set)
+		records = append(records, stored...)
+	}
+	if len(held) == 0 {
+		return nil
+	}
+
+	// drop removes the records matching the predicate from the domains of their instance
+	drop := func(match func(r *types.Record) bool) {
+		records = slices.DeleteFunc(records, func(r *types.Record) bool {
+			if !match(r) {
+				return false
+			}
+			if set := held[r.InstanceID]; set != nil {
+				if set[r.Domain]--; set[r.Domain] <= 0 {
+					delete(set, r.Domain)
+				}
+			}
+			return true
+		})
+	}
+
+	for i, change := range changes {
+		switch change.Op {
+		case types.ChangeDelete:
+			drop(func(r *types.Record) bool { return r.Domain == change.Domain })
+		case types.ChangeDeleteInstance:
+			drop(func(r *types.Record) bool { return r.InstanceID == change.InstanceID })
+		case types.ChangeDeleteID:
+			drop(func(r *types.Record) bool { return r.ID != 0 && r.ID == change.ID })
+		case types.ChangeCreate, types.ChangeUpdate, types.ChangeUpsert:
+			if change.Record == nil {
+				continue
+			}
+			if change.Op != types.ChangeCreate {
+				// the stored record is replaced, possibly in another instance
+				drop(func(r *types.Record) bool { return sameRecord(r, change.Record) })
+			}
+			records = append(records, change.Record)
+
+			id := change.Record.InstanceID
+			set, ok := held[id]
+			if !ok {
+				continue
+			}
+			set[change.Record.Domain]++
+			if limit := s.policy.limit(id); len(set) > limit && len(set) > start[id] {
+				return changeError(changes, i, &QuotaError{InstanceID: id, Limit: limit})
+			}
+		}
+	}
+	return nil
+}
+
+// storesRecord reports whether a change operation stores its record
+func storesRecord(op types.ChangeOp) bool {
+	return op == types.ChangeCreate || op == types.ChangeUpdate || op == types.ChangeUpsert
+}
+
+// sameRecord reports whether an update or upsert of r identifies the stored record
+// Records are identified by ID, or by domain and validity window if the update has no ID
+func sameRecord(stored, r *types.Record) bool {
+	if r.ID != 0 {
+		return stored.ID == r.ID
+	}
+	return stored.Domain == r.Domain && stored.SameWindow(r)
+}
+
+// changeError attributes an error to the change at index i of a batch
+func changeError(changes []types.Change, i int, err error) error {
+	if len(changes) == 1 {
+		return err
+	}
+	return fmt.Errorf("change %d: %w", i, err)
+}
@@ -0,0 +1,178 @@
+package tsdns
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+func TestCheckQuotas(t *testing.T) {
+	instanceRecord := func(instanceID int64, domain string) *types.Record {
+		return &types.Record{InstanceID: instanceID, Domain: domain, Target: "192.0.2.1", Port: 9987}
+	}
+	create := func(instanceID int64, domain string) types.Change {
+		return types.Change{Op: types.ChangeCreate, Record: instanceRecord(instanceID, domain)}
+	}
+
+	// instance 1 holds a and b with records 1 and 2, instance 2 holds c with record 3
+	tests := []struct {
+		name    string
+		changes []types.Change
+		// wantInstance is the instance over its quota, 0 if the batch passes
+		wantInstance int64
+	}{
+		{
+			name:    "within quota",
+			changes: []types.Change{create(2, "d.example.com")},
+		},
+		{
+			name:         "over quota",
+			changes:      []types.Change{create(1, "d.example.com")},
+			wantInstance: 1,
+		},
+		{
+			name:         "over quota within the batch",
+			changes:      []types.Change{create(2, "d.example.com"), create(2, "e.example.com")},
+			wantInstance: 2,
+		},
+		{
+			name:    "second window of a held domain",
+			changes: []types.Change{{Op: types.ChangeUpsert, Record: instanceRecord(1, "a.example.com")}},
+		},
+		{
+			name:    "delete by domain frees a slot",
+			changes: []types.Change{{Op: types.ChangeDelete, Domain: "a.example.com"}, create(1, "d.example.com")},
+		},
+		{
+			name:    "delete by ID frees a slot",
+			changes: []types.Change{{Op: types.ChangeDeleteID, ID: 2}, create(1, "d.example.com")},
+		},
+		{
+			name:    "delete instance frees every slot",
+			changes: []types.Change{{Op: types.ChangeDeleteInstance, InstanceID: 1}, create(1, "d.example.com"), create(1, "e.example.com")},
+		},
+		{
+			name:         "delete of another domain frees nothing",
+			changes:      []types.Change{{Op: types.ChangeDelete, Domain: "c.example.com"}, create(1, "d.example.com")},
+			wantInstance: 1,
+		},
+		{
+			name: "update moving a record frees its slot",
+			changes: []types.Change{
+				{Op: types.ChangeUpdate, Record: &types.Record{ID: 1, InstanceID: 2, Domain: "a.example.com", Target: "192.0.2.1"}},
+				create(1, "d.example.com"),
+			},
+		},
+		{
+			name: "update moving a record takes a slot",
+			changes: []types.Change{
+				{Op: types.ChangeUpdate, Record: &types.Record{ID: 1, InstanceID: 2, Domain: "a.example.com", Target: "192.0.2.1"}},
+				create(2, "d.example.com"),
+			},
+			wantInstance: 2,
+		},
+		{
+			name: "upsert moving a record frees its slot",
+			changes: []types.Change{
+				{Op: types.ChangeUpsert, Record: instanceRecord(2, "b.example.com")},
+				create(1, "d.example.com"),
+			},
+		},
+		{
+			name: "record created and deleted in the batch",
+			changes: []types.Change{
+				create(2, "d.example.com"),
+				{Op: types.ChangeDelete, Domain: "d.example.com"},
+				create(2, "e.example.com"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, func(b *ServerBuilder) *ServerBuilder {
+				return b.WithPolicy(Policy{MaxDomains: 2})
+			})
+			mustCreate(t, s, instanceRecord(1, "a.example.com"), instanceRecord(1, "b.example.com"), instanceRecord(2, "c.example.com"))
+
+			ctx, cancel := s.repoContext(context.Background())
+			defer cancel()
+			err := s.checkQuotas(ctx, tt.changes)
+
+			var quotaErr *QuotaError
+			switch {
+			case tt.wantInstance == 0 && err != nil:
+				t.Fatalf("checkQuotas error: %v", err)
+			case tt.wantInstance != 0 && !errors.As(err, &quotaErr):
+				t.Fatalf("checkQuotas error = %v, want a quota error for instance %d", err, tt.wantInstance)
+			case tt.wantInstance != 0 && quotaErr.InstanceID != tt.wantInstance:
+				t.Fatalf("checkQuotas rejected instance %d, want %d", quotaErr.InstanceID, tt.wantInstance)
+			}
+		})
+	}
+}
+
+func TestReservedDomains(t *testing.T) {
+	s := newTestServer(t, func(b *ServerBuilder) *ServerBuilder {
+		return b.WithPolicy(Policy{Reserved: []string{"admin.*"}, Blocked: []string{"*teamspeak*"}})
+	})
+
+	tests := []struct {
+		name       string
+		instanceID int64
+		domain     string
+		want       error
+	}{
+		{"operator record on a reserved domain", 0, "admin.example.com", nil},
+		{"instance record on a reserved domain", 1, "ADMIN.example.org", ErrDomainReserved},
+		{"instance record on a free domain", 1, "play.example.com", nil},
+		{"operator record on a blocked domain", 0, "teamspeak.example.com", ErrDomainReserved},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.AddInstanceRecord(tt.instanceID, tt.domain, "192.0.2.1", 9987)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("AddInstanceRecord(%d, %s) error = %v, want %v", tt.instanceID, tt.domain, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyTightenedAfterCreate(t *testing.T) {
+	s := newTestServer(t, nil)
+	mustCreate(t, s,
+		&types.Record{InstanceID: 1, Domain: "admin.example.com", Target: "192.0.2.1", Port: 9987},
+		&types.Record{InstanceID: 1, Domain: "teamspeak.example.com", Target: "192.0.2.1", Port: 9988},
+	)
+	s.policy = &Policy{Reserved: []string{"admin.*"}, Blocked: []string{"*teamspeak*"}}
+
+	// records stored before the patterns were added keep being managed
+	steps := []struct {
+		name string
+		call func() error
+	}{
+		{"DisableInstance", func() error { return s.DisableInstance(1) }},
+		{"EnableInstance", func() error { return s.EnableInstance(1) }},
+		{"SetRecordEnabled", func() error { return s.SetRecordEnabled("teamspeak.example.com", false) }},
+		{"MoveInstance", func() error { return s.MoveInstance(1, "192.0.2.2", 0) }},
+		{"TransferDomain", func() error { return s.TransferDomain("admin.example.com", 2) }},
+	}
+	for _, step := range steps {
+		if err := step.call(); err != nil {
+			t.Fatalf("%s error: %v", step.name, err)
+		}
+	}
+
+	// new records and records renamed to a matching domain are still rejected
+	if err := s.AddInstanceRecord(1, "admin.example.org", "192.0.2.1", 9987); !errors.Is(err, ErrDomainReserved) {
+		t.Fatalf("AddInstanceRecord error = %v, want ErrDomainReserved", err)
+	}
+	record, _ := s.cache.lookup("admin.example.com")
+	renamed := record.Clone()
+	renamed.Domain = "teamspeak.example.org"
+	if err := s.UpdateRecord(renamed); !errors.Is(err, ErrDomainReserved) {
+		t.Fatalf("UpdateRecord renaming to a blocked domain error = %v, want ErrDomainReserved", err)
+	}
+}
@@ -0,0 +1,78 @@
+package tsdns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+// AllocatePort reserves the next free port of a host for an instance, such as for a new virtual server
+//
+// It returns the lowest port of the range set with WithPortRange that is neither reserved nor
+// used by a record pointing to target. Concurrent calls, also of other servers sharing the
+// repository, never return the same port. The port stays reserved until the instance is
+// removed with RemoveInstanceRecords or ChangeDeleteInstance, or the leases of all of its
+// records have ended. It returns types.ErrNoFreePort if the range is exhausted and
+// types.ErrNotSupported if the repository cannot reserve ports
+func (s *Server) AllocatePort(target string, instanceID int64) (int32, error) {
+	return s.AllocatePortContext(context.Background(), target, instanceID)
+}
+
+// AllocatePortContext is like AllocatePort but uses the deadline, tenant and actor of ctx
+func (s *Server) AllocatePortContext(ctx context.Context, target string, instanceID int64) (int32, error) {
+	if reason := checkTarget(target); reason != "" {
+		verr := &ValidationError{}
+		verr.add("target", "%s", reason)
+		return 0, verr
+	}
+	if s.ports.Max == 0 {
+		return 0, fmt.Errorf("no port range configured, see WithPortRange")
+	}
+	reserver, ok := s.repository.(types.PortRepository)
+	if !ok {
+		return 0, types.ErrNotSupported
+	}
+
+	ctx, cancel := s.repoContext(ctx)
+	defer cancel()
+
+	port, err := reserver.ReservePort(ctx, target, s.ports, instanceID)
+	if err != nil {
+		return 0, err
+	}
+	s.logger.Info("Allocated port %d of %s to instance %d\n", port, target, instanceID)
+	return port, nil
+}
+
+// releaseRemovedPorts releases the ports of the instances removed by applied changes
+func (s *Server) releaseRemovedPorts(ctx context.Context, changes []types.Change) error {
+	var errs []error
+	for _, change := range changes {
+		if change.Op == types.ChangeDeleteInstance {
+			errs = append(errs, s.releasePorts(ctx, change.InstanceID))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// releasePorts releases the ports reserved for an instance, if the repository reserves ports
+func (s *Server) releasePorts(ctx context.Context, instanceID int64) error {
+	reserver, ok := s.repository.(types.PortRepository)
+	if !ok {
+		return nil
+	}
+
+	ctx, cancel := s.repoContext(ctx)
+	defer cancel()
+
+	count, err := reserver.ReleasePorts(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("release ports of instance %d: %w", instanceID, err)
+	}
+	if count > 0 {
+		s.logger.Info("Released %d ports of instance %d\n", count, instanceID)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package tsdns
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+func TestPortsReleasedWithInstance(t *testing.T) {
+	const host = "ts1.example.com"
+
+	tests := []struct {
+		name string
+		// lease is the lease of the record of instance 1, 0 for none
+		lease  time.Duration
+		remove func(s *Server) error
+		// keep reports whether instance 1 keeps its port
+		keep bool
+	}{
+		{
+			name:   "RemoveInstanceRecords",
+			remove: func(s *Server) error { return s.RemoveInstanceRecords(1) },
+		},
+		{
+			name: "Apply with ChangeDeleteInstance",
+			remove: func(s *Server) error {
+				return s.Apply(types.Change{Op: types.ChangeDeleteInstance, InstanceID: 1})
+			},
+		},
+		{
+			name:  "reaper",
+			lease: 50 * time.Millisecond,
+			remove: func(s *Server) error {
+				time.Sleep(100 * time.Millisecond)
+				return s.reapExpired()
+			},
+		},
+		{
+			name:  "reaper before the lease ended",
+			lease: time.Hour,
+			remove: func(s *Server) error {
+				return s.reapExpired()
+			},
+			keep: true,
+		},
+		{
+			name:   "removing another instance",
+			remove: func(s *Server) error { return s.RemoveInstanceRecords(2) },
+			keep:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, func(b *ServerBuilder) *ServerBuilder {
+				return b.WithPortRange(9987, 9987)
+			})
+
+			port, err := s.AllocatePort(host, 1)
+			if err != nil {
+				t.Fatalf("AllocatePort error: %v", err)
+			}
+			record := &types.Record{InstanceID: 1, Domain: "a.example.com", Target: host, Port: port}
+			if tt.lease != 0 {
+				expiresAt := time.Now().Add(tt.lease)
+				record.ExpiresAt = &expiresAt
+			}
+			mustCreate(t, s, record)
+
+			if err = tt.remove(s); err != nil {
+				t.Fatalf("removing the instance error: %v", err)
+			}
+
+			port, err = s.AllocatePort(host, 3)
+			switch {
+			case tt.keep && !errors.Is(err, types.ErrNoFreePort):
+				t.Fatalf("AllocatePort = %d, %v, want ErrNoFreePort while instance 1 holds the port", port, err)
+			case !tt.keep && (err != nil || port != 9987):
+				t.Fatalf("AllocatePort = %d, %v, want the released port 9987", port, err)
+			}
+		})
+	}
+}
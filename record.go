@@ -1,47 +1,346 @@
 package tsdns
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/honeybbq/tsdns-go/types"
 )
 
+// ListRecords returns a page of records matching the options
+// It reads the repository, so records added by other servers are included
+func (s *Server) ListRecords(opts types.ListOptions) (*types.RecordPage, error) {
+	return s.ListRecordsContext(context.Background(), opts)
+}
+
+// ListRecordsContext is like ListRecords but uses the deadline, tenant and actor of ctx
+func (s *Server) ListRecordsContext(ctx context.Context, opts types.ListOptions) (*types.RecordPage, error) {
+	ctx, cancel := s.repoContext(ctx)
+	defer cancel()
+
+	return s.repository.List(ctx, opts)
+}
+
+// FindRecordsByTarget returns the records pointing to a host, such as before decommissioning it
+// Soft-deleted records are included if includeDeleted is set
+func (s *Server) FindRecordsByTarget(target string, includeDeleted bool) ([]*types.Record, error) {
+	return s.FindRecordsByTargetContext(context.Background(), target, includeDeleted)
+}
+
+// FindRecordsByTargetContext is like FindRecordsByTarget but uses the deadline, tenant and actor of ctx
+func (s *Server) FindRecordsByTargetContext(ctx context.Context, target string, includeDeleted bool) ([]*types.Record, error) {
+	ctx, cancel := s.repoContext(ctx)
+	defer cancel()
+
+	return s.repository.FindByTarget(ctx, target, includeDeleted)
+}
+
+// FindRecordsByInstance returns the records of an instance
+// Soft-deleted records are included if includeDeleted is set
+func (s *Server) FindRecordsByInstance(instanceID int64, includeDeleted bool) ([]*types.Record, error) {
+	return s.FindRecordsByInstanceContext(context.Background(), instanceID, includeDeleted)
+}
+
+// FindRecordsByInstanceContext is like FindRecordsByInstance but uses the deadline, tenant and actor of ctx
+func (s *Server) FindRecordsByInstanceContext(ctx context.Context, instanceID int64, includeDeleted bool) ([]*types.Record, error) {
+	ctx, cancel := s.repoContext(ctx)
+	defer cancel()
+
+	return s.repository.FindByInstanceID(ctx, instanceID, includeDeleted)
+}
+
 // AddRecord adds a new DNS record to the system
+// Like every method storing a record, it checks the record with ValidateRecord and the
+// policy set with WithPolicy first. The record belongs to no instance, so it may use
+// reserved domains and counts against no quota, see AddInstanceRecord.
 // Updates both repository and cache immediately
 func (s *Server) AddRecord(domain, target string, port int32) error {
+	return s.AddRecordContext(context.Background(), domain, target, port)
+}
+
+// AddRecordContext is like AddRecord but uses the deadline, tenant and actor of ctx
+func (s *Server) AddRecordContext(ctx context.Context, domain, target string, port int32) error {
+	return s.AddInstanceRecordContext(ctx, 0, domain, target, port)
+}
+
+// AddInstanceRecord adds a new DNS record held by an instance
+// The domain must not match a reserved pattern of the policy and counts against the quota of the instance.
+// Updates both repository and cache immediately
+func (s *Server) AddInstanceRecord(instanceID int64, domain, target string, port int32) error {
+	return s.AddInstanceRecordContext(context.Background(), instanceID, domain, target, port)
+}
+
+// AddInstanceRecordContext is like AddInstanceRecord but uses the deadline, tenant and actor of ctx
+func (s *Server) AddInstanceRecordContext(ctx context.Context, instanceID int64, domain, target string, port int32) error {
+	return s.applyOne(ctx, types.Change{
+		Op: types.ChangeCreate,
+		Record: &types.Record{
+			InstanceID: instanceID,
+			Domain:     domain,
+			Target:     target,
+			Port:       port,
+		},
+	})
+}
+
+// CreateRecord adds a fully specified DNS record to the system
+// Use it to set fields AddRecord does not cover, such as InstanceID or ExpiresAt
+// Updates both repository and cache immediately
+func (s *Server) CreateRecord(record *types.Record) error {
+	return s.CreateRecordContext(context.Background(), record)
+}
+
+// CreateRecordContext is like CreateRecord but uses the deadline, tenant and actor of ctx
+func (s *Server) CreateRecordContext(ctx context.Context, record *types.Record) error {
+	return s.applyOne(ctx, types.Change{
+		Op:     types.ChangeCreate,
+		Record: record,
+	})
+}
+
+// UpdateRecord changes the instance, target, port, lease, validity window and metadata of an existing record
+// The record is identified by its ID, or by domain and validity window if ID is 0
+// Updates both repository and cache immediately
+func (s *Server) UpdateRecord(record *types.Record) error {
+	return s.UpdateRecordContext(context.Background(), record)
+}
+
+// UpdateRecordContext is like UpdateRecord but uses the deadline, tenant and actor of ctx
+func (s *Server) UpdateRecordContext(ctx context.Context, record *types.Record) error {
+	return s.applyOne(ctx, types.Change{
+		Op:     types.ChangeUpdate,
+		Record: record,
+	})
+}
+
+// SetRecord points a domain at target and port, creating the record if it does not exist
+// The instance, lease and metadata of an existing record are kept
+// Updates both repository and cache immediately
+func (s *Server) SetRecord(domain, target string, port int32) error {
+	return s.SetRecordContext(context.Background(), domain, target, port)
+}
+
+// SetRecordContext is like SetRecord but uses the deadline, tenant and actor of ctx
+func (s *Server) SetRecordContext(ctx context.Context, domain, target string, port int32) error {
 	record := &types.Record{
 		Domain: domain,
 		Target: target,
 		Port:   port,
 	}
+	for _, existing := range s.cache.domain(s.tenant(ctx), domain) {
+		if !existing.Scheduled() {
+			record.InstanceID = existing.InstanceID
+			record.ExpiresAt = existing.ExpiresAt
+			record.Labels = existing.Labels
+			record.Description = existing.Description
+			record.Owner = existing.Owner
+			record.Disabled = existing.Disabled
+		}
+	}
 
-	err := s.repository.Create(record)
-	if err != nil {
-		return err
+	return s.applyOne(ctx, types.Change{
+		Op:     types.ChangeUpsert,
+		Record: record,
+	})
+}
+
+// ScheduleRecord adds a DNS record that answers for a domain only between from and until
+// While active it takes precedence over the unscheduled record of the domain, which answers
+// again once the window ends. It returns the ID of the scheduled record
+func (s *Server) ScheduleRecord(domain, target string, port int32, from, until time.Time) (int64, error) {
+	return s.ScheduleRecordContext(context.Background(), domain, target, port, from, until)
+}
+
+// ScheduleRecordContext is like ScheduleRecord but uses the deadline, tenant and actor of ctx
+func (s *Server) ScheduleRecordContext(ctx context.Context, domain, target string, port int32, from, until time.Time) (int64, error) {
+	if !until.After(from) {
+		return 0, fmt.Errorf("%w: schedule must end after it starts", types.ErrInvalidRecord)
 	}
 
-	// update cache
-	return s.loadCache()
+	record := &types.Record{
+		Domain:     domain,
+		Target:     target,
+		Port:       port,
+		ValidFrom:  &from,
+		ValidUntil: &until,
+	}
+	if err := s.CreateRecordContext(ctx, record); err != nil {
+		return 0, err
+	}
+	return record.ID, nil
 }
 
-// RemoveRecord deletes a DNS record by domain name
+// RemoveRecordByID deletes a single DNS record, such as a scheduled one
+// Updates both repository and cache immediately
+func (s *Server) RemoveRecordByID(id int64) error {
+	return s.RemoveRecordByIDContext(context.Background(), id)
+}
+
+// RemoveRecordByIDContext is like RemoveRecordByID but uses the deadline, tenant and actor of ctx
+func (s *Server) RemoveRecordByIDContext(ctx context.Context, id int64) error {
+	return s.applyOne(ctx, types.Change{
+		Op: types.ChangeDeleteID,
+		ID: id,
+	})
+}
+
+// RemoveRecord deletes all DNS records of a domain name, including scheduled ones
 // Updates both repository and cache immediately
 func (s *Server) RemoveRecord(domain string) error {
-	err := s.repository.Delete(domain)
-	if err != nil {
-		return err
-	}
+	return s.RemoveRecordContext(context.Background(), domain)
+}
 
-	// 立即更新缓存
-	return s.loadCache()
+// RemoveRecordContext is like RemoveRecord but uses the deadline, tenant and actor of ctx
+func (s *Server) RemoveRecordContext(ctx context.Context, domain string) error {
+	return s.applyOne(ctx, types.Change{
+		Op:     types.ChangeDelete,
+		Domain: domain,
+	})
 }
 
 // RemoveInstanceRecords removes all records associated with an instance
+// The ports reserved for the instance with AllocatePort are released afterwards.
 // Updates both repository and cache immediately
 func (s *Server) RemoveInstanceRecords(instanceID int64) error {
-	err := s.repository.DeleteByInstanceID(instanceID)
+	return s.RemoveInstanceRecordsContext(context.Background(), instanceID)
+}
+
+// RemoveInstanceRecordsContext is like RemoveInstanceRecords but uses the deadline, tenant and actor of ctx
+func (s *Server) RemoveInstanceRecordsContext(ctx context.Context, instanceID int64) error {
+	return s.applyOne(ctx, types.Change{
+		Op:         types.ChangeDeleteInstance,
+		InstanceID: instanceID,
+	})
+}
+
+// Apply applies a batch of changes to the repository in order, either all of them or none
+// The cache is updated once after the batch, so bulk provisioning does not reload it per record
+//
+// Repositories without batch support apply the changes one by one instead. Such a batch
+// stops at the first failing change, changes applied before it are kept and reflected in the cache.
+// Instances removed with ChangeDeleteInstance lose their reserved ports like with RemoveInstanceRecords
+func (s *Server) Apply(changes ...types.Change) error {
+	return s.ApplyContext(context.Background(), changes...)
+}
+
+// ApplyContext is like Apply but uses the deadline, tenant and actor of ctx
+func (s *Server) ApplyContext(ctx context.Context, changes ...types.Change) error {
+	if err := validateChanges(changes); err != nil {
+		return err
+	}
+	if err := s.checkAliases(s.tenant(ctx), changes); err != nil {
+		return err
+	}
+
+	ctx, cancel := s.repoContext(ctx)
+	defer cancel()
+
+	unlock, err := s.enforcePolicy(ctx, changes)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = s.repository.Apply(ctx, changes)
+	if errors.Is(err, types.ErrNotSupported) {
+		return s.applyEach(ctx, changes)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.updateCache(s.tenant(ctx), changes...)
+	return s.releaseRemovedPorts(ctx, changes)
+}
+
+// updateRecords applies fn to copies of records and stores the changed ones in one batch
+// fn reports whether it changed the record. It returns the number of stored records
+func (s *Server) updateRecords(ctx context.Context, records []*types.Record, fn func(r *types.Record) bool) (int, error) {
+	var changes []types.Change
+	for _, record := range records {
+		updated := record.Clone()
+		if fn(updated) {
+			changes = append(changes, types.Change{Op: types.ChangeUpdate, Record: updated})
+		}
+	}
+	if len(changes) == 0 {
+		return 0, nil
+	}
+	if err := s.ApplyContext(ctx, changes...); err != nil {
+		return 0, err
+	}
+	return len(changes), nil
+}
+
+// applyEach applies changes one by one and updates the cache with the applied ones
+// The ports of removed instances are released even if a later change fails
+func (s *Server) applyEach(ctx context.Context, changes []types.Change) error {
+	applied := make([]types.Change, 0, len(changes))
+	defer func() {
+		s.updateCache(s.tenant(ctx), applied...)
+	}()
+
+	for i, change := range changes {
+		if err := s.applyChange(ctx, change); err != nil {
+			return errors.Join(fmt.Errorf("change %d: %w", i, err), s.releaseRemovedPorts(ctx, applied))
+		}
+		applied = append(applied, change)
+	}
+	return s.releaseRemovedPorts(ctx, applied)
+}
+
+// applyOne applies a single change to the repository and the cache
+func (s *Server) applyOne(ctx context.Context, change types.Change) error {
+	if err := validateChange(change); err != nil {
+		return err
+	}
+	if err := s.checkAliases(s.tenant(ctx), []types.Change{change}); err != nil {
+		return err
+	}
+
+	ctx, cancel := s.repoContext(ctx)
+	defer cancel()
+
+	unlock, err := s.enforcePolicy(ctx, []types.Change{change})
 	if err != nil {
 		return err
 	}
+	defer unlock()
+
+	if err := s.applyChange(ctx, change); err != nil {
+		return err
+	}
+	s.updateCache(s.tenant(ctx), change)
+	return s.releaseRemovedPorts(ctx, []types.Change{change})
+}
 
-	// update cache
-	return s.loadCache()
+// applyChange applies a single change to the repository
+func (s *Server) applyChange(ctx context.Context, change types.Change) error {
+	switch change.Op {
+	case types.ChangeCreate:
+		if change.Record == nil {
+			return fmt.Errorf("%w: create change without record", types.ErrInvalidRecord)
+		}
+		return s.repository.Create(ctx, change.Record)
+	case types.ChangeDelete:
+		return s.repository.Delete(ctx, change.Domain)
+	case types.ChangeDeleteInstance:
+		return s.repository.DeleteByInstanceID(ctx, change.InstanceID)
+	case types.ChangeDeleteID:
+		return s.repository.DeleteByID(ctx, change.ID)
+	case types.ChangeUpdate:
+		if change.Record == nil {
+			return fmt.Errorf("%w: update change without record", types.ErrInvalidRecord)
+		}
+		return s.repository.Update(ctx, change.Record)
+	case types.ChangeUpsert:
+		if change.Record == nil {
+			return fmt.Errorf("%w: upsert change without record", types.ErrInvalidRecord)
+		}
+		return s.repository.Upsert(ctx, change.Record)
+	default:
+		return fmt.Errorf("unknown change operation %d", change.Op)
+	}
 }
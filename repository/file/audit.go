@@ -0,0 +1,214 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+// auditSuffix is appended to the repository file path to name the audit log
+//
+// The audit log is a sidecar file holding one JSON encoded entry per line, it is
+// appended to and only rewritten to drop the entries of purged records
+const auditSuffix = ".audit"
+
+// maxAuditLine is the longest audit log line that can be read back
+const maxAuditLine = 1 << 20
+
+// audit queues an audit entry for the change of a record, it is written by commit
+// before and after are copied, either may be nil
+func (f *repository) audit(action types.AuditAction, before, after *types.Record) {
+	entry := &types.AuditEntry{Action: action}
+	if before != nil {
+		r := *before
+		entry.Old, entry.RecordID, entry.Domain = &r, r.ID, r.Domain
+	}
+	if after != nil {
+		r := *after
+		entry.New, entry.RecordID, entry.Domain = &r, r.ID, r.Domain
+	}
+	f.pending = append(f.pending, entry)
+}
+
+// commit appends the queued audit entries to the audit log and writes the records to file
+// The entries go first so an applied change is never left unaudited, they are cut off the
+// log again if the records cannot be written
+func (f *repository) commit(ctx context.Context) error {
+	nextAuditID := f.nextAuditID
+	size, err := f.flushAudit(ctx)
+	if err != nil {
+		f.nextAuditID = nextAuditID
+		return err
+	}
+	if err = f.save(); err != nil {
+		f.nextAuditID = nextAuditID
+		if size >= 0 {
+			if _err := os.Truncate(f.filePath+auditSuffix, size); _err != nil {
+				return fmt.Errorf("%v, failed to remove its audit entries: %v", err, _err)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// flushAudit appends the queued audit entries to the audit log
+// The entries are attributed to the actor and tenant of ctx. It returns the size of the
+// log before the entries were appended, or -1 if there were none
+func (f *repository) flushAudit(ctx context.Context) (int64, error) {
+	pending := f.pending
+	f.pending = nil
+	if len(pending) == 0 {
+		return -1, nil
+	}
+
+	actor := types.ActorFrom(ctx)
+	tenant := tenantOf(ctx)
+	now := time.Now()
+	var data []byte
+	for _, entry := range pending {
+		entry.ID = f.nextAuditID
+		entry.Tenant = tenant
+		entry.Actor = actor
+		entry.Time = now
+		f.nextAuditID++
+
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return -1, fmt.Errorf("failed to encode audit entry: %v", err)
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	file, err := os.OpenFile(f.filePath+auditSuffix, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return -1, fmt.Errorf("failed to open audit log: %v", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return -1, fmt.Errorf("failed to open audit log: %v", err)
+	}
+	if _, err = file.Write(data); err != nil {
+		// a torn entry would merge with the next one appended
+		file.Truncate(info.Size())
+		return -1, fmt.Errorf("failed to write audit log: %v", err)
+	}
+	return info.Size(), nil
+}
+
+// readAudit calls fn for every entry of the audit log in order
+func (f *repository) readAudit(fn func(entry *types.AuditEntry)) error {
+	file, err := os.Open(f.filePath + auditSuffix)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %v", err)
+	}
+	defer file.Close()
+
+	return scanAudit(file, fn)
+}
+
+// scanAudit calls fn for every entry read from r
+// Lines that cannot be decoded, such as one torn by a crash, are skipped
+func scanAudit(r io.Reader, fn func(entry *types.AuditEntry)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxAuditLine)
+	for scanner.Scan() {
+		var entry types.AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		fn(&entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read audit log: %v", err)
+	}
+	return nil
+}
+
+// loadAudit finds the next audit entry ID from the audit log
+// A torn last line is terminated so entries appended later stay readable
+func (f *repository) loadAudit() error {
+	data, err := os.ReadFile(f.filePath + auditSuffix)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read audit log: %v", err)
+	}
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		if err = os.WriteFile(f.filePath+auditSuffix, append(data, '\n'), 0644); err != nil {
+			return fmt.Errorf("failed to repair audit log: %v", err)
+		}
+	}
+
+	return scanAudit(bytes.NewReader(data), func(entry *types.AuditEntry) {
+		if entry.ID >= f.nextAuditID {
+			f.nextAuditID = entry.ID + 1
+		}
+	})
+}
+
+// purgeAudit removes the audit entries of the given records of a tenant from the audit log
+// The log is replaced by a rewritten copy, so a failure leaves it as it was
+func (f *repository) purgeAudit(tenant string, ids map[int64]bool) error {
+	data, err := os.ReadFile(f.filePath + auditSuffix)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read audit log: %v", err)
+	}
+
+	kept := make([]byte, 0, len(data))
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		var entry types.AuditEntry
+		if json.Unmarshal(line, &entry) == nil && entry.Tenant == tenant && ids[entry.RecordID] {
+			continue
+		}
+		kept = append(kept, line...)
+	}
+	if len(kept) == len(data) {
+		return nil
+	}
+
+	tmp := f.filePath + auditSuffix + ".tmp"
+	if err = os.WriteFile(tmp, kept, 0644); err != nil {
+		return fmt.Errorf("failed to write audit log: %v", err)
+	}
+	if err = os.Rename(tmp, f.filePath+auditSuffix); err != nil {
+		return fmt.Errorf("failed to replace audit log: %v", err)
+	}
+	return nil
+}
+
+// History retrieves the audit entries of a domain, oldest first
+func (f *repository) History(ctx context.Context, domain string) ([]*types.AuditEntry, error) {
+	if err := f.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer f.mu.RUnlock()
+
+	tenant := tenantOf(ctx)
+	var entries []*types.AuditEntry
+	err := f.readAudit(func(entry *types.AuditEntry) {
+		if entry.Tenant == tenant && entry.Domain == domain {
+			entries = append(entries, entry)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
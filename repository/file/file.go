@@ -1,8 +1,11 @@
 package file
 
 import (
+	"context"
 	"fmt"
+	"maps"
 	"os"
+	"sort"
 	"sync"
 	"time"
 
@@ -11,9 +14,17 @@ import (
 )
 
 type repository struct {
-	filePath string
-	records  map[string]*types.Record
-	mu       sync.RWMutex
+	filePath     string
+	records      map[int64]*types.Record
+	nextID       int64
+	nextAuditID  int64
+	ports        []*types.PortReservation
+	maintenance  []*types.Maintenance
+	nextWindowID int64
+	// pending holds the audit entries of the current operation until it is committed
+	pending []*types.AuditEntry
+	closed  bool
+	mu      sync.RWMutex
 }
 
 // NewRepository creates a new file-based repository
@@ -21,14 +32,26 @@ type repository struct {
 // filePath: path to the binary file for storage
 func NewRepository(filePath string) (types.RecordRepository, error) {
 	repo := &repository{
-		filePath: filePath,
-		records:  make(map[string]*types.Record),
+		filePath:     filePath,
+		records:      make(map[int64]*types.Record),
+		nextID:       1,
+		nextAuditID:  1,
+		nextWindowID: 1,
 	}
 
 	// Load existing records if file exists
 	if err := repo.load(); err != nil && !os.IsNotExist(err) {
 		return nil, fmt.Errorf("failed to load records: %v", err)
 	}
+	if err := repo.loadAudit(); err != nil {
+		return nil, err
+	}
+	if err := repo.loadPorts(); err != nil {
+		return nil, err
+	}
+	if err := repo.loadMaintenance(); err != nil {
+		return nil, err
+	}
 
 	return repo, nil
 }
@@ -54,10 +77,37 @@ func (f *repository) load() error {
 	}
 
 	// Only try to decode if file is not empty
-	if len(data) > 0 {
-		if err := msgpack.Unmarshal(data, &f.records); err != nil {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var records []*types.Record
+	if err := msgpack.Unmarshal(data, &records); err != nil {
+		// Files written before scheduled records were supported hold a map keyed by domain
+		var legacy map[string]*types.Record
+		if _err := msgpack.Unmarshal(data, &legacy); _err != nil {
 			return fmt.Errorf("failed to decode records: %v", err)
 		}
+		for _, record := range legacy {
+			records = append(records, record)
+		}
+	}
+
+	for _, record := range records {
+		if record.ID >= f.nextID {
+			f.nextID = record.ID + 1
+		}
+	}
+	for _, record := range records {
+		if record.ID == 0 {
+			record.ID = f.nextID
+			f.nextID++
+		}
+		// records written before versioning start at the first version
+		if record.Version == 0 {
+			record.Version = 1
+		}
+		f.records[record.ID] = record
 	}
 
 	return nil
@@ -65,7 +115,12 @@ func (f *repository) load() error {
 
 // save writes records to file
 func (f *repository) save() error {
-	data, err := msgpack.Marshal(f.records)
+	records := make([]*types.Record, 0, len(f.records))
+	for _, record := range f.records {
+		records = append(records, record)
+	}
+
+	data, err := msgpack.Marshal(records)
 	if err != nil {
 		return fmt.Errorf("failed to encode records: %v", err)
 	}
@@ -77,72 +132,526 @@ func (f *repository) save() error {
 	return nil
 }
 
-// Find retrieves all records
-func (f *repository) Find() ([]*types.Record, error) {
+// copyRecords returns copies of the records so callers cannot modify the stored ones
+func copyRecords(records []*types.Record) []*types.Record {
+	copies := make([]*types.Record, len(records))
+	for i, record := range records {
+		copies[i] = record.Clone()
+	}
+	return copies
+}
+
+// lock acquires the write lock unless ctx is done or the repository is closed
+// The caller must release the lock with f.mu.Unlock when lock returns nil
+func (f *repository) lock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	if err := f.usable(ctx); err != nil {
+		f.mu.Unlock()
+		return err
+	}
+	// entries queued by a failed operation are dropped
+	f.pending = nil
+	return nil
+}
+
+// rlock acquires the read lock unless ctx is done or the repository is closed
+// The caller must release the lock with f.mu.RUnlock when rlock returns nil
+func (f *repository) rlock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	f.mu.RLock()
+	if err := f.usable(ctx); err != nil {
+		f.mu.RUnlock()
+		return err
+	}
+	return nil
+}
+
+// usable reports why the repository cannot be used, the caller must hold a lock
+func (f *repository) usable(ctx context.Context) error {
+	if f.closed {
+		return types.ErrClosed
+	}
+	// waiting for the lock may have taken longer than the deadline
+	return ctx.Err()
+}
+
+// tenantOf returns the tenant the operation of ctx is scoped to
+func tenantOf(ctx context.Context) string {
+	tenant, _ := types.TenantFrom(ctx)
+	return tenant
+}
+
+// live returns all records of a domain in a tenant that are not deleted
+func (f *repository) live(tenant, domain string) []*types.Record {
+	var records []*types.Record
+	for _, record := range f.records {
+		if record.Tenant == tenant && record.Domain == domain && record.DeletedAt == nil {
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+// Find retrieves all records
+func (f *repository) Find(ctx context.Context) ([]*types.Record, error) {
+	if err := f.rlock(ctx); err != nil {
+		return nil, err
+	}
 	defer f.mu.RUnlock()
 
+	tenant := tenantOf(ctx)
 	records := make([]*types.Record, 0, len(f.records))
 	for _, record := range f.records {
-		if record.DeletedAt == nil {
+		if record.Tenant == tenant && record.DeletedAt == nil {
 			records = append(records, record)
 		}
 	}
-	return records, nil
+	return copyRecords(records), nil
 }
 
-// FindByDomain finds a record by domain name
-func (f *repository) FindByDomain(domain string) (*types.Record, error) {
-	f.mu.RLock()
+// List retrieves a page of live records matching the options
+func (f *repository) List(ctx context.Context, opts types.ListOptions) (*types.RecordPage, error) {
+	if err := f.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer f.mu.RUnlock()
+
+	tenant := tenantOf(ctx)
+	records := make([]*types.Record, 0, len(f.records))
+	for _, record := range f.records {
+		if record.Tenant == tenant && record.DeletedAt == nil {
+			records = append(records, record)
+		}
+	}
+
+	page, err := types.ListRecords(records, opts)
+	if err != nil {
+		return nil, err
+	}
+	page.Records = copyRecords(page.Records)
+	return page, nil
+}
+
+// FindByDomain finds the record currently answering for a domain name
+func (f *repository) FindByDomain(ctx context.Context, domain string) (*types.Record, error) {
+	if err := f.rlock(ctx); err != nil {
+		return nil, err
+	}
 	defer f.mu.RUnlock()
 
-	record, exists := f.records[domain]
-	if !exists || record.DeletedAt != nil {
-		return nil, fmt.Errorf("record not found")
+	record := types.Active(f.live(tenantOf(ctx), domain), time.Now())
+	if record == nil {
+		return nil, types.ErrNotFound
 	}
-	return record, nil
+	return record.Clone(), nil
+}
+
+// FindByTarget retrieves the records pointing to a host ordered by ID
+func (f *repository) FindByTarget(ctx context.Context, target string, includeDeleted bool) ([]*types.Record, error) {
+	return f.findAll(ctx, includeDeleted, func(r *types.Record) bool {
+		return r.Target == target
+	})
+}
+
+// FindByInstanceID retrieves the records of an instance ordered by ID
+func (f *repository) FindByInstanceID(ctx context.Context, instanceID int64, includeDeleted bool) ([]*types.Record, error) {
+	return f.findAll(ctx, includeDeleted, func(r *types.Record) bool {
+		return r.InstanceID == instanceID
+	})
+}
+
+// findAll returns copies of the matching records ordered by ID
+func (f *repository) findAll(ctx context.Context, includeDeleted bool, match func(r *types.Record) bool) ([]*types.Record, error) {
+	if err := f.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer f.mu.RUnlock()
+
+	tenant := tenantOf(ctx)
+	var records []*types.Record
+	for _, record := range f.records {
+		if record.Tenant == tenant && (includeDeleted || record.DeletedAt == nil) && match(record) {
+			records = append(records, record)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ID < records[j].ID
+	})
+	return copyRecords(records), nil
 }
 
 // Create creates a new record
-func (f *repository) Create(record *types.Record) error {
-	f.mu.Lock()
+func (f *repository) Create(ctx context.Context, record *types.Record) error {
+	if err := f.lock(ctx); err != nil {
+		return err
+	}
 	defer f.mu.Unlock()
 
+	if err := f.create(tenantOf(ctx), record); err != nil {
+		return err
+	}
+	return f.commit(ctx)
+}
+
+// create stores a new record in a tenant, the caller must hold the write lock
+func (f *repository) create(tenant string, record *types.Record) error {
+	if err := record.Validate(); err != nil {
+		return err
+	}
+	if err := types.AssignTenant(record, tenant); err != nil {
+		return err
+	}
+
+	for _, existing := range f.live(tenant, record.Domain) {
+		if existing.SameWindow(record) {
+			return types.ErrDomainExists
+		}
+	}
+
 	// Set timestamps
 	now := time.Now()
+	record.ID = f.nextID
 	record.CreatedAt = now
 	record.UpdatedAt = now
+	record.Version = 1
+	f.nextID++
 
-	f.records[record.Domain] = record
-	return f.save()
+	stored := record.Clone()
+	f.records[record.ID] = stored
+	f.audit(types.AuditCreate, nil, stored)
+	return nil
 }
 
-// Delete removes a record
-func (f *repository) Delete(domain string) error {
-	f.mu.Lock()
+// find returns the live record of the record tenant identified by ID, or by domain and
+// validity window if ID is 0
+func (f *repository) find(record *types.Record) *types.Record {
+	if record.ID != 0 {
+		existing, exists := f.records[record.ID]
+		if !exists || existing.Tenant != record.Tenant || existing.DeletedAt != nil {
+			return nil
+		}
+		return existing
+	}
+
+	for _, existing := range f.live(record.Tenant, record.Domain) {
+		if existing.SameWindow(record) {
+			return existing
+		}
+	}
+	return nil
+}
+
+// update copies the mutable fields of record into the stored record
+func (f *repository) update(existing, record *types.Record) error {
+	if record.Version != 0 && record.Version != existing.Version {
+		return &types.VersionConflictError{
+			ID:       existing.ID,
+			Domain:   existing.Domain,
+			Expected: record.Version,
+			Actual:   existing.Version,
+		}
+	}
+
+	updated := *existing
+	updated.InstanceID = record.InstanceID
+	updated.Target = record.Target
+	updated.Port = record.Port
+	updated.ExpiresAt = record.ExpiresAt
+	updated.ValidFrom = record.ValidFrom
+	updated.ValidUntil = record.ValidUntil
+	updated.Labels = maps.Clone(record.Labels)
+	updated.Description = record.Description
+	updated.Owner = record.Owner
+	updated.Disabled = record.Disabled
+	updated.AliasOf = record.AliasOf
+	updated.UpdatedAt = time.Now()
+	updated.Version++
+	if err := updated.Validate(); err != nil {
+		return err
+	}
+
+	// another live record may already use the new validity window
+	for _, other := range f.live(updated.Tenant, updated.Domain) {
+		if other.ID != updated.ID && other.SameWindow(&updated) {
+			return types.ErrDomainExists
+		}
+	}
+
+	f.audit(types.AuditUpdate, existing, &updated)
+	*existing = updated
+	*record = *updated.Clone()
+	return nil
+}
+
+// Update changes the instance, target, port, lease, validity window and metadata of a live record
+func (f *repository) Update(ctx context.Context, record *types.Record) error {
+	if err := f.lock(ctx); err != nil {
+		return err
+	}
+	defer f.mu.Unlock()
+
+	if err := f.updateRecord(tenantOf(ctx), record); err != nil {
+		return err
+	}
+	return f.commit(ctx)
+}
+
+// updateRecord updates the live record of a tenant identified by record
+func (f *repository) updateRecord(tenant string, record *types.Record) error {
+	if err := types.AssignTenant(record, tenant); err != nil {
+		return err
+	}
+
+	existing := f.find(record)
+	if existing == nil {
+		return types.ErrNotFound
+	}
+	return f.update(existing, record)
+}
+
+// Upsert updates the live record with the same domain and validity window, or creates it
+func (f *repository) Upsert(ctx context.Context, record *types.Record) error {
+	if err := f.lock(ctx); err != nil {
+		return err
+	}
 	defer f.mu.Unlock()
 
-	record, exists := f.records[domain]
-	if !exists {
-		return fmt.Errorf("record not found")
+	if err := f.upsert(tenantOf(ctx), record); err != nil {
+		return err
+	}
+	return f.commit(ctx)
+}
+
+// upsert updates the live record of a tenant with the same domain and validity window, or creates it
+func (f *repository) upsert(tenant string, record *types.Record) error {
+	if err := record.Validate(); err != nil {
+		return err
+	}
+	if err := types.AssignTenant(record, tenant); err != nil {
+		return err
+	}
+
+	lookup := *record
+	lookup.ID = 0
+	if existing := f.find(&lookup); existing != nil {
+		return f.update(existing, record)
+	}
+	return f.create(tenant, record)
+}
+
+// Retarget points the live records of the from endpoint matching the options to the to endpoint
+// The file is written once, after all records have been changed
+func (f *repository) Retarget(ctx context.Context, from, to types.Endpoint, opts types.RetargetOptions) ([]types.RecordDiff, error) {
+	if err := types.ValidateRetarget(from, to, opts); err != nil {
+		return nil, err
+	}
+	if err := f.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer f.mu.Unlock()
+
+	tenant := tenantOf(ctx)
+	var diffs []types.RecordDiff
+	for _, record := range f.records {
+		if record.Tenant != tenant || record.DeletedAt != nil || !types.MatchRetarget(record, from, opts) {
+			continue
+		}
+		retargeted, err := types.RetargetRecord(record, to, opts)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, types.RecordDiff{Before: record.Clone(), After: retargeted})
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Before.ID < diffs[j].Before.ID
+	})
+	if opts.DryRun || len(diffs) == 0 {
+		return diffs, nil
+	}
+
+	now := time.Now()
+	for _, diff := range diffs {
+		diff.After.UpdatedAt = now
+		diff.After.Version++
+		record := f.records[diff.After.ID]
+		record.Target, record.Port, record.UpdatedAt, record.Version = diff.After.Target, diff.After.Port, now, diff.After.Version
+		f.audit(types.AuditUpdate, diff.Before, record)
+	}
+	return diffs, f.commit(ctx)
+}
+
+// Delete removes all records of a domain
+func (f *repository) Delete(ctx context.Context, domain string) error {
+	if err := f.lock(ctx); err != nil {
+		return err
+	}
+	defer f.mu.Unlock()
+
+	if err := f.delete(tenantOf(ctx), domain); err != nil {
+		return err
+	}
+	return f.commit(ctx)
+}
+
+// delete removes all records of a domain in a tenant
+func (f *repository) delete(tenant, domain string) error {
+	records := f.live(tenant, domain)
+	if len(records) == 0 {
+		return types.ErrNotFound
 	}
 
 	now := time.Now()
+	for _, record := range records {
+		f.softDelete(record, now)
+	}
+	return nil
+}
+
+// softDelete marks a record deleted at the given time
+func (f *repository) softDelete(record *types.Record, now time.Time) {
+	f.audit(types.AuditDelete, record, nil)
 	record.DeletedAt = &now
 	record.UpdatedAt = now
+}
 
-	return f.save()
+// DeleteByID removes a single record
+func (f *repository) DeleteByID(ctx context.Context, id int64) error {
+	if err := f.lock(ctx); err != nil {
+		return err
+	}
+	defer f.mu.Unlock()
+
+	if err := f.deleteByID(tenantOf(ctx), id); err != nil {
+		return err
+	}
+	return f.commit(ctx)
+}
+
+// deleteByID removes a single record of a tenant
+func (f *repository) deleteByID(tenant string, id int64) error {
+	record, exists := f.records[id]
+	if !exists || record.Tenant != tenant || record.DeletedAt != nil {
+		return types.ErrNotFound
+	}
+
+	f.softDelete(record, time.Now())
+	return nil
 }
 
 // DeleteByInstanceID removes all records for a specific instance
-func (f *repository) DeleteByInstanceID(instanceID int64) error {
-	f.mu.Lock()
+func (f *repository) DeleteByInstanceID(ctx context.Context, instanceID int64) error {
+	if err := f.lock(ctx); err != nil {
+		return err
+	}
+	defer f.mu.Unlock()
+
+	f.deleteByInstanceID(tenantOf(ctx), instanceID)
+	return f.commit(ctx)
+}
+
+// deleteByInstanceID removes all records for a specific instance of a tenant
+func (f *repository) deleteByInstanceID(tenant string, instanceID int64) {
+	now := time.Now()
+	for _, record := range f.records {
+		if record.Tenant == tenant && record.InstanceID == instanceID && record.DeletedAt == nil {
+			f.softDelete(record, now)
+		}
+	}
+}
+
+// Apply applies a batch of changes in order and writes the file once
+// A failing change restores the records as they were before the batch
+func (f *repository) Apply(ctx context.Context, changes []types.Change) error {
+	if err := f.lock(ctx); err != nil {
+		return err
+	}
+	defer f.mu.Unlock()
+
+	tenant := tenantOf(ctx)
+	records, nextID := f.backup()
+	for i, change := range changes {
+		if err := f.applyChange(tenant, change); err != nil {
+			f.records, f.nextID, f.pending = records, nextID, nil
+			return fmt.Errorf("change %d: %w", i, err)
+		}
+	}
+
+	if err := f.commit(ctx); err != nil {
+		f.records, f.nextID = records, nextID
+		return err
+	}
+	return nil
+}
+
+// applyChange applies a single change to the in-memory records of a tenant
+func (f *repository) applyChange(tenant string, change types.Change) error {
+	switch change.Op {
+	case types.ChangeCreate:
+		return f.create(tenant, change.Record)
+	case types.ChangeUpdate:
+		return f.updateRecord(tenant, change.Record)
+	case types.ChangeUpsert:
+		return f.upsert(tenant, change.Record)
+	case types.ChangeDelete:
+		return f.delete(tenant, change.Domain)
+	case types.ChangeDeleteID:
+		return f.deleteByID(tenant, change.ID)
+	case types.ChangeDeleteInstance:
+		f.deleteByInstanceID(tenant, change.InstanceID)
+		return nil
+	default:
+		return fmt.Errorf("unknown change operation %d", change.Op)
+	}
+}
+
+// backup returns a deep copy of the records and the next ID to restore them after a failed batch
+func (f *repository) backup() (map[int64]*types.Record, int64) {
+	records := make(map[int64]*types.Record, len(f.records))
+	for id, record := range f.records {
+		r := *record
+		records[id] = &r
+	}
+	return records, f.nextID
+}
+
+// Renew sets the lease expiry of all records of a domain
+func (f *repository) Renew(ctx context.Context, domain string, expiresAt time.Time) error {
+	if err := f.lock(ctx); err != nil {
+		return err
+	}
+	defer f.mu.Unlock()
+
+	records := f.live(tenantOf(ctx), domain)
+	if len(records) == 0 {
+		return types.ErrNotFound
+	}
+
+	now := time.Now()
+	for _, record := range records {
+		record.ExpiresAt = &expiresAt
+		record.UpdatedAt = now
+	}
+
+	return f.save()
+}
+
+// RenewByInstanceID sets the lease expiry of all records for a specific instance
+func (f *repository) RenewByInstanceID(ctx context.Context, instanceID int64, expiresAt time.Time) error {
+	if err := f.lock(ctx); err != nil {
+		return err
+	}
 	defer f.mu.Unlock()
 
+	tenant := tenantOf(ctx)
 	now := time.Now()
 	for _, record := range f.records {
-		if record.InstanceID == instanceID {
-			record.DeletedAt = &now
+		if record.Tenant == tenant && record.InstanceID == instanceID && record.DeletedAt == nil {
+			record.ExpiresAt = &expiresAt
 			record.UpdatedAt = now
 		}
 	}
@@ -150,7 +659,134 @@ func (f *repository) DeleteByInstanceID(instanceID int64) error {
 	return f.save()
 }
 
+// DeleteExpired removes all records whose lease or validity window ended at or before the given time
+func (f *repository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	if err := f.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer f.mu.Unlock()
+
+	tenant := tenantOf(ctx)
+	var count int64
+	deletedAt := time.Now()
+	for _, record := range f.records {
+		if record.Tenant == tenant && record.DeletedAt == nil && record.Expired(now) {
+			f.softDelete(record, deletedAt)
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, nil
+	}
+
+	return count, f.commit(ctx)
+}
+
+// ListDeleted retrieves all soft-deleted records
+func (f *repository) ListDeleted(ctx context.Context) ([]*types.Record, error) {
+	if err := f.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer f.mu.RUnlock()
+
+	tenant := tenantOf(ctx)
+	var records []*types.Record
+	for _, record := range f.records {
+		if record.Tenant == tenant && record.DeletedAt != nil {
+			records = append(records, record)
+		}
+	}
+	return copyRecords(records), nil
+}
+
+// Restore undeletes the records of a domain removed by its most recent deletion
+func (f *repository) Restore(ctx context.Context, domain string) ([]*types.Record, error) {
+	if err := f.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer f.mu.Unlock()
+
+	tenant := tenantOf(ctx)
+	var latest *time.Time
+	for _, record := range f.records {
+		if record.Tenant == tenant && record.Domain == domain && record.DeletedAt != nil && (latest == nil || record.DeletedAt.After(*latest)) {
+			latest = record.DeletedAt
+		}
+	}
+	if latest == nil {
+		return nil, types.ErrNotFound
+	}
+
+	var restored []*types.Record
+	live := f.live(tenant, domain)
+	for _, record := range f.records {
+		if record.Tenant != tenant || record.Domain != domain || record.DeletedAt == nil || !record.DeletedAt.Equal(*latest) {
+			continue
+		}
+		for _, other := range live {
+			if other.SameWindow(record) {
+				return nil, types.ErrDomainExists
+			}
+		}
+		restored = append(restored, record)
+	}
+
+	now := time.Now()
+	for _, record := range restored {
+		before := *record
+		record.DeletedAt = nil
+		record.UpdatedAt = now
+		f.audit(types.AuditRestore, &before, record)
+	}
+
+	return copyRecords(restored), f.commit(ctx)
+}
+
+// Purge permanently removes records soft-deleted before the given time
+func (f *repository) Purge(ctx context.Context, olderThan time.Time) (int64, error) {
+	if err := f.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer f.mu.Unlock()
+
+	tenant := tenantOf(ctx)
+	purged := make(map[int64]*types.Record)
+	for id, record := range f.records {
+		if record.Tenant == tenant && record.DeletedAt != nil && record.DeletedAt.Before(olderThan) {
+			purged[id] = record
+		}
+	}
+	if len(purged) == 0 {
+		return 0, nil
+	}
+
+	// the audit entries go first, records left by a failed save are purged again later
+	ids := make(map[int64]bool, len(purged))
+	for id := range purged {
+		ids[id] = true
+	}
+	if err := f.purgeAudit(tenant, ids); err != nil {
+		return 0, err
+	}
+	for id := range purged {
+		delete(f.records, id)
+	}
+	if err := f.save(); err != nil {
+		maps.Copy(f.records, purged)
+		return 0, err
+	}
+	return int64(len(purged)), nil
+}
+
 // Close implements repository interface
+// Closing an already closed repository is a no-op
 func (f *repository) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	f.closed = true
 	return f.save()
 }
@@ -0,0 +1,20 @@
+package file_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/honeybbq/tsdns-go/repository/file"
+	"github.com/honeybbq/tsdns-go/repository/repotest"
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+func TestRepository(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) types.RecordRepository {
+		repo, err := file.NewRepository(filepath.Join(t.TempDir(), "records.bin"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	})
+}
@@ -0,0 +1,142 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/honeybbq/tsdns-go/types"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// maintenanceSuffix is appended to the repository file path to name the maintenance window file
+const maintenanceSuffix = ".maintenance"
+
+// loadMaintenance reads the maintenance windows from file
+func (f *repository) loadMaintenance() error {
+	data, err := os.ReadFile(f.filePath + maintenanceSuffix)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read maintenance windows: %v", err)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	if err = msgpack.Unmarshal(data, &f.maintenance); err != nil {
+		return fmt.Errorf("failed to decode maintenance windows: %v", err)
+	}
+	for _, m := range f.maintenance {
+		if m.ID >= f.nextWindowID {
+			f.nextWindowID = m.ID + 1
+		}
+	}
+	return nil
+}
+
+// saveMaintenance writes the maintenance windows to file
+func (f *repository) saveMaintenance() error {
+	data, err := msgpack.Marshal(f.maintenance)
+	if err != nil {
+		return fmt.Errorf("failed to encode maintenance windows: %v", err)
+	}
+	if err = os.WriteFile(f.filePath+maintenanceSuffix, data, 0644); err != nil {
+		return fmt.Errorf("failed to write maintenance windows: %v", err)
+	}
+	return nil
+}
+
+// FindMaintenance retrieves the maintenance windows ordered by ID
+func (f *repository) FindMaintenance(ctx context.Context) ([]*types.Maintenance, error) {
+	if err := f.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer f.mu.RUnlock()
+
+	tenant := tenantOf(ctx)
+	var windows []*types.Maintenance
+	for _, m := range f.maintenance {
+		if m.Tenant == tenant {
+			window := *m
+			windows = append(windows, &window)
+		}
+	}
+	return windows, nil
+}
+
+// CreateMaintenance stores a maintenance window
+func (f *repository) CreateMaintenance(ctx context.Context, m *types.Maintenance) error {
+	_, err := f.storeMaintenance(ctx, m, nil)
+	return err
+}
+
+// ReplaceMaintenance removes the windows of the scope of m running at now and stores m
+func (f *repository) ReplaceMaintenance(ctx context.Context, m *types.Maintenance, now time.Time) ([]int64, error) {
+	return f.storeMaintenance(ctx, m, func(window *types.Maintenance) bool {
+		return window.Scope == m.Scope && window.ActiveAt(now)
+	})
+}
+
+// storeMaintenance stores a maintenance window after removing the windows of its tenant matching replace
+// The file is written once. It returns the IDs of the removed windows
+func (f *repository) storeMaintenance(ctx context.Context, m *types.Maintenance, replace func(window *types.Maintenance) bool) ([]int64, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	if err := f.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer f.mu.Unlock()
+
+	window := *m
+	window.ID = f.nextWindowID
+	window.Tenant = tenantOf(ctx)
+	window.CreatedAt = time.Now()
+
+	previous := f.maintenance
+	var replaced []int64
+	f.maintenance = slices.DeleteFunc(slices.Clone(f.maintenance), func(existing *types.Maintenance) bool {
+		if replace == nil || existing.Tenant != window.Tenant || !replace(existing) {
+			return false
+		}
+		replaced = append(replaced, existing.ID)
+		return true
+	})
+	f.maintenance = append(f.maintenance, &window)
+	if err := f.saveMaintenance(); err != nil {
+		f.maintenance = previous
+		return nil, err
+	}
+
+	f.nextWindowID++
+	m.ID, m.Tenant, m.CreatedAt = window.ID, window.Tenant, window.CreatedAt
+	return replaced, nil
+}
+
+// DeleteMaintenance removes a maintenance window
+func (f *repository) DeleteMaintenance(ctx context.Context, id int64) error {
+	if err := f.lock(ctx); err != nil {
+		return err
+	}
+	defer f.mu.Unlock()
+
+	tenant := tenantOf(ctx)
+	i := slices.IndexFunc(f.maintenance, func(m *types.Maintenance) bool {
+		return m.Tenant == tenant && m.ID == id
+	})
+	if i < 0 {
+		return fmt.Errorf("%w: maintenance window %d", types.ErrNotFound, id)
+	}
+
+	previous := f.maintenance
+	f.maintenance = slices.Delete(slices.Clone(f.maintenance), i, i+1)
+	if err := f.saveMaintenance(); err != nil {
+		f.maintenance = previous
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/honeybbq/tsdns-go/types"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// portSuffix is appended to the repository file path to name the port reservation file
+const portSuffix = ".ports"
+
+// loadPorts reads the port reservations from file
+func (f *repository) loadPorts() error {
+	data, err := os.ReadFile(f.filePath + portSuffix)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read port reservations: %v", err)
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	if err = msgpack.Unmarshal(data, &f.ports); err != nil {
+		return fmt.Errorf("failed to decode port reservations: %v", err)
+	}
+	return nil
+}
+
+// savePorts writes the port reservations to file
+func (f *repository) savePorts() error {
+	data, err := msgpack.Marshal(f.ports)
+	if err != nil {
+		return fmt.Errorf("failed to encode port reservations: %v", err)
+	}
+	if err = os.WriteFile(f.filePath+portSuffix, data, 0644); err != nil {
+		return fmt.Errorf("failed to write port reservations: %v", err)
+	}
+	return nil
+}
+
+// ReservePort reserves the lowest free port of target within ports for an instance
+func (f *repository) ReservePort(ctx context.Context, target string, ports types.PortRange, instanceID int64) (int32, error) {
+	if err := ports.Validate(); err != nil {
+		return 0, err
+	}
+	if err := f.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer f.mu.Unlock()
+
+	used := make(map[int32]bool)
+	for _, reservation := range f.ports {
+		if reservation.Target == target {
+			used[reservation.Port] = true
+		}
+	}
+	// ports are shared by all tenants of the host
+	for _, record := range f.records {
+		if record.Target == target && record.DeletedAt == nil {
+			used[record.Port] = true
+		}
+	}
+
+	for port := ports.Min; port <= ports.Max; port++ {
+		if used[port] {
+			continue
+		}
+		f.ports = append(f.ports, &types.PortReservation{
+			Tenant:     tenantOf(ctx),
+			InstanceID: instanceID,
+			Target:     target,
+			Port:       port,
+			CreatedAt:  time.Now(),
+		})
+		if err := f.savePorts(); err != nil {
+			f.ports = f.ports[:len(f.ports)-1]
+			return 0, err
+		}
+		return port, nil
+	}
+	return 0, fmt.Errorf("%w: %s %s", types.ErrNoFreePort, target, ports)
+}
+
+// ReleasePorts releases the ports reserved for an instance
+func (f *repository) ReleasePorts(ctx context.Context, instanceID int64) (int64, error) {
+	if err := f.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer f.mu.Unlock()
+
+	tenant := tenantOf(ctx)
+	kept := slices.DeleteFunc(slices.Clone(f.ports), func(reservation *types.PortReservation) bool {
+		return reservation.Tenant == tenant && reservation.InstanceID == instanceID
+	})
+	released := int64(len(f.ports) - len(kept))
+	if released == 0 {
+		return 0, nil
+	}
+
+	previous := f.ports
+	f.ports = kept
+	if err := f.savePorts(); err != nil {
+		f.ports = previous
+		return 0, err
+	}
+	return released, nil
+}
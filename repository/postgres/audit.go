@@ -0,0 +1,123 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/honeybbq/tsdns-go/repository/postgres/model"
+	"github.com/honeybbq/tsdns-go/repository/postgres/query"
+	"github.com/honeybbq/tsdns-go/types"
+
+	"gorm.io/gorm/clause"
+)
+
+// audit records changes of DNS records in the audit table using the given query
+// before and after hold the records before and after the change, either may be nil
+func (p *repository) audit(ctx context.Context, tx *query.Query, action types.AuditAction, before, after []*types.Record) error {
+	entries := make([]*model.RecordAudit, max(len(before), len(after)))
+	if len(entries) == 0 {
+		return nil
+	}
+
+	actor := types.ActorFrom(ctx)
+	tenant := tenantOf(ctx)
+	now := time.Now()
+	for i := range entries {
+		entry := &model.RecordAudit{
+			Tenant:    tenant,
+			Action:    string(action),
+			Actor:     actor,
+			CreatedAt: now,
+		}
+		var err error
+		if before != nil {
+			entry.RecordID, entry.Domain = before[i].ID, before[i].Domain
+			if entry.OldValue, err = encodeRecord(before[i]); err != nil {
+				return err
+			}
+		}
+		if after != nil {
+			entry.RecordID, entry.Domain = after[i].ID, after[i].Domain
+			if entry.NewValue, err = encodeRecord(after[i]); err != nil {
+				return err
+			}
+		}
+		entries[i] = entry
+	}
+	return tx.RecordAudit.WithContext(ctx).Create(entries...)
+}
+
+// deleteRecords soft-deletes the live DNS records matched by do and audits them
+// It returns the records as they were before the deletion
+func (p *repository) deleteRecords(ctx context.Context, tx *query.Query, do query.IRecordDo) ([]*types.Record, error) {
+	models, err := do.Clauses(clause.Locking{Strength: "UPDATE"}).Order(tx.Record.ID).Find()
+	if err != nil || len(models) == 0 {
+		return nil, err
+	}
+
+	ids := make([]int64, len(models))
+	records := make([]*types.Record, len(models))
+	for i, m := range models {
+		ids[i] = m.ID
+		records[i] = p.toRecord(m)
+	}
+
+	if _, err = tx.Record.WithContext(ctx).Where(tx.Record.ID.In(ids...)).Delete(); err != nil {
+		return nil, err
+	}
+	return records, p.audit(ctx, tx, types.AuditDelete, records, nil)
+}
+
+// History retrieves the audit entries of a domain, oldest first
+func (p *repository) History(ctx context.Context, domain string) ([]*types.AuditEntry, error) {
+	a := p.q.RecordAudit
+	models, err := a.WithContext(ctx).Where(a.Tenant.Eq(tenantOf(ctx)), a.Domain.Eq(domain)).Order(a.ID).Find()
+	if err != nil {
+		return nil, p.mapError(err)
+	}
+
+	entries := make([]*types.AuditEntry, len(models))
+	for i, m := range models {
+		entry := &types.AuditEntry{
+			ID:       m.ID,
+			Tenant:   m.Tenant,
+			RecordID: m.RecordID,
+			Domain:   m.Domain,
+			Action:   types.AuditAction(m.Action),
+			Actor:    m.Actor,
+			Time:     m.CreatedAt,
+		}
+		if entry.Old, err = decodeRecord(m.OldValue); err != nil {
+			return nil, err
+		}
+		if entry.New, err = decodeRecord(m.NewValue); err != nil {
+			return nil, err
+		}
+		entries[i] = entry
+	}
+	return entries, nil
+}
+
+// encodeRecord encodes a record for a JSONB audit column
+func encodeRecord(r *types.Record) (*string, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit record: %v", err)
+	}
+	value := string(data)
+	return &value, nil
+}
+
+// decodeRecord decodes a record from a JSONB audit column, NULL decodes to nil
+func decodeRecord(value *string) (*types.Record, error) {
+	if value == nil {
+		return nil, nil
+	}
+	var r types.Record
+	if err := json.Unmarshal([]byte(*value), &r); err != nil {
+		return nil, fmt.Errorf("failed to decode audit record: %v", err)
+	}
+	return &r, nil
+}
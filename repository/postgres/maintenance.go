@@ -0,0 +1,125 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/honeybbq/tsdns-go/repository/postgres/model"
+	"github.com/honeybbq/tsdns-go/repository/postgres/query"
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+// toMaintenance converts a database model to a maintenance window
+func toMaintenance(m *model.MaintenanceWindow) *types.Maintenance {
+	return &types.Maintenance{
+		ID:     m.ID,
+		Tenant: m.Tenant,
+		Scope: types.MaintenanceScope{
+			Kind:       types.MaintenanceKind(m.Scope),
+			InstanceID: m.InstanceID,
+			Domain:     m.Domain,
+		},
+		Target:    types.Endpoint{Target: m.Target, Port: m.Port},
+		From:      m.ValidFrom,
+		Until:     m.ValidUntil,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+// FindMaintenance retrieves the maintenance windows ordered by ID
+func (p *repository) FindMaintenance(ctx context.Context) ([]*types.Maintenance, error) {
+	w := p.q.MaintenanceWindow
+	models, err := w.WithContext(ctx).Where(w.Tenant.Eq(tenantOf(ctx))).Order(w.ID).Find()
+	if err != nil {
+		return nil, p.mapError(err)
+	}
+
+	windows := make([]*types.Maintenance, len(models))
+	for i, m := range models {
+		windows[i] = toMaintenance(m)
+	}
+	return windows, nil
+}
+
+// toMaintenanceModel converts a maintenance window of the tenant of ctx to a database model
+func toMaintenanceModel(ctx context.Context, m *types.Maintenance) *model.MaintenanceWindow {
+	return &model.MaintenanceWindow{
+		Tenant:     tenantOf(ctx),
+		Scope:      string(m.Scope.Kind),
+		InstanceID: m.Scope.InstanceID,
+		Domain:     m.Scope.Domain,
+		Target:     m.Target.Target,
+		Port:       m.Target.Port,
+		ValidFrom:  m.From,
+		ValidUntil: m.Until,
+	}
+}
+
+// CreateMaintenance stores a maintenance window
+func (p *repository) CreateMaintenance(ctx context.Context, m *types.Maintenance) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	window := toMaintenanceModel(ctx, m)
+	if err := p.q.MaintenanceWindow.WithContext(ctx).Create(window); err != nil {
+		return p.mapError(err)
+	}
+	m.ID, m.Tenant, m.CreatedAt = window.ID, window.Tenant, window.CreatedAt
+	return nil
+}
+
+// ReplaceMaintenance removes the windows of the scope of m running at now and stores m in one transaction
+// Replacements of the same scope are serialized by a transaction level advisory lock
+func (p *repository) ReplaceMaintenance(ctx context.Context, m *types.Maintenance, now time.Time) ([]int64, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	window := toMaintenanceModel(ctx, m)
+	var replaced []int64
+	err := p.transaction(ctx, func(tx *query.Query) error {
+		w := tx.MaintenanceWindow
+		lock := fmt.Sprintf("maintenance_window %s %s", window.Tenant, m.Scope)
+		if err := w.WithContext(ctx).UnderlyingDB().Exec("SELECT pg_advisory_xact_lock(hashtext(?))", lock).Error; err != nil {
+			return err
+		}
+
+		models, err := w.WithContext(ctx).
+			Where(w.Tenant.Eq(window.Tenant), w.Scope.Eq(window.Scope), w.InstanceID.Eq(window.InstanceID), w.Domain.Eq(window.Domain)).
+			Find()
+		if err != nil {
+			return err
+		}
+		for _, existing := range models {
+			if toMaintenance(existing).ActiveAt(now) {
+				replaced = append(replaced, existing.ID)
+			}
+		}
+		if len(replaced) > 0 {
+			if _, err = w.WithContext(ctx).Where(w.ID.In(replaced...)).Delete(); err != nil {
+				return err
+			}
+		}
+		return w.WithContext(ctx).Create(window)
+	})
+	if err != nil {
+		return nil, p.mapError(err)
+	}
+	m.ID, m.Tenant, m.CreatedAt = window.ID, window.Tenant, window.CreatedAt
+	return replaced, nil
+}
+
+// DeleteMaintenance removes a maintenance window
+func (p *repository) DeleteMaintenance(ctx context.Context, id int64) error {
+	w := p.q.MaintenanceWindow
+	info, err := w.WithContext(ctx).Where(w.Tenant.Eq(tenantOf(ctx)), w.ID.Eq(id)).Delete()
+	if err != nil {
+		return p.mapError(err)
+	}
+	if info.RowsAffected == 0 {
+		return fmt.Errorf("%w: maintenance window %d", types.ErrNotFound, id)
+	}
+	return nil
+}
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_record_expires_at;
+
+ALTER TABLE record DROP COLUMN IF EXISTS expires_at;
\ No newline at end of file
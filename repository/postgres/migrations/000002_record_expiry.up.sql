@@ -0,0 +1,3 @@
+ALTER TABLE record ADD COLUMN IF NOT EXISTS expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_record_expires_at ON record (expires_at) WHERE deleted_at IS NULL AND expires_at IS NOT NULL;
\ No newline at end of file
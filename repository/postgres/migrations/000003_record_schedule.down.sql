@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_record_valid_until;
+DROP INDEX IF EXISTS uniq_record_domain_window;
+DROP INDEX IF EXISTS uniq_record_domain_live;
+
+-- record_domain_key covers soft-deleted rows too, which cannot repeat a domain under it anymore:
+-- only the live row of a domain, or else its last deleted one, is kept.
+-- A domain holding several live records, such as scheduled ones, must be cleaned up first
+DELETE FROM record r WHERE r.deleted_at IS NOT NULL AND EXISTS (
+    SELECT 1 FROM record o WHERE o.domain = r.domain AND o.id <> r.id AND (o.deleted_at IS NULL OR o.id > r.id)
+);
+ALTER TABLE record ADD CONSTRAINT record_domain_key UNIQUE (domain);
+
+ALTER TABLE record DROP COLUMN IF EXISTS valid_until;
+ALTER TABLE record DROP COLUMN IF EXISTS valid_from;
\ No newline at end of file
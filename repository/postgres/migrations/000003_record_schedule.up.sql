@@ -0,0 +1,12 @@
+ALTER TABLE record ADD COLUMN IF NOT EXISTS valid_from TIMESTAMP WITH TIME ZONE;
+ALTER TABLE record ADD COLUMN IF NOT EXISTS valid_until TIMESTAMP WITH TIME ZONE;
+
+-- A domain may now hold several records with distinct validity windows
+ALTER TABLE record DROP CONSTRAINT IF EXISTS record_domain_key;
+DROP INDEX IF EXISTS uniq_record_domain_live;
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_record_domain_window ON record (
+    domain,
+    COALESCE(valid_from, '-infinity'::TIMESTAMP WITH TIME ZONE),
+    COALESCE(valid_until, 'infinity'::TIMESTAMP WITH TIME ZONE)
+);
+CREATE INDEX IF NOT EXISTS idx_record_valid_until ON record (valid_until) WHERE deleted_at IS NULL AND valid_until IS NOT NULL;
@@ -0,0 +1,8 @@
+-- Soft-deleted rows may repeat the domain and window of a live one, so uniqueness stays limited
+-- to live rows, otherwise migrating down fails once a deleted domain was created again
+DROP INDEX IF EXISTS uniq_record_domain_window_live;
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_record_domain_window ON record (
+    domain,
+    COALESCE(valid_from, '-infinity'::TIMESTAMP WITH TIME ZONE),
+    COALESCE(valid_until, 'infinity'::TIMESTAMP WITH TIME ZONE)
+) WHERE deleted_at IS NULL;
\ No newline at end of file
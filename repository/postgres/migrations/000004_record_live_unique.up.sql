@@ -0,0 +1,7 @@
+-- Deletes are soft, so uniqueness must only apply to live rows for a deleted domain to be created again
+DROP INDEX IF EXISTS uniq_record_domain_window;
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_record_domain_window_live ON record (
+    domain,
+    COALESCE(valid_from, '-infinity'::TIMESTAMP WITH TIME ZONE),
+    COALESCE(valid_until, 'infinity'::TIMESTAMP WITH TIME ZONE)
+) WHERE deleted_at IS NULL;
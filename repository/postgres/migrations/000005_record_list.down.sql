@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_record_created_at;
+DROP INDEX IF EXISTS idx_record_domain_pattern;
+DROP INDEX IF EXISTS idx_record_instance;
\ No newline at end of file
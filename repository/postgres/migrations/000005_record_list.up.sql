@@ -0,0 +1,4 @@
+-- Indexes backing List, prefix matches need pattern ops as the database collation may not be C
+CREATE INDEX IF NOT EXISTS idx_record_instance ON record (instance_id, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_record_domain_pattern ON record (domain varchar_pattern_ops) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_record_created_at ON record (created_at, id) WHERE deleted_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_record_instance;
+CREATE INDEX IF NOT EXISTS idx_record_instance ON record (instance_id, id) WHERE deleted_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,3 @@
+-- FindByInstanceID may include deleted records, so the instance index must cover them like idx_record_target
+DROP INDEX IF EXISTS idx_record_instance;
+CREATE INDEX IF NOT EXISTS idx_record_instance ON record (instance_id, deleted_at);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE record DROP COLUMN IF EXISTS version;
\ No newline at end of file
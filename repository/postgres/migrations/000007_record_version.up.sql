@@ -0,0 +1,2 @@
+-- Records start at version 1 and every update increments it, see Record.Version
+ALTER TABLE record ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS record_audit;
\ No newline at end of file
@@ -0,0 +1,13 @@
+-- record_id is not a foreign key, Purge removes the audit entries of the records it removes
+CREATE TABLE IF NOT EXISTS record_audit (
+    id BIGSERIAL PRIMARY KEY,
+    record_id BIGINT NOT NULL,
+    domain VARCHAR(255) NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    actor VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    old_value JSONB,
+    new_value JSONB
+);
+
+CREATE INDEX IF NOT EXISTS idx_record_audit_domain ON record_audit (domain, id);
\ No newline at end of file
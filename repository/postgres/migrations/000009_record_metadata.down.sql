@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_record_owner;
+DROP INDEX IF EXISTS idx_record_labels;
+ALTER TABLE record DROP COLUMN IF EXISTS disabled;
+ALTER TABLE record DROP COLUMN IF EXISTS owner;
+ALTER TABLE record DROP COLUMN IF EXISTS description;
+ALTER TABLE record DROP COLUMN IF EXISTS labels;
\ No newline at end of file
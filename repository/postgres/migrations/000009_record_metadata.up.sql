@@ -0,0 +1,8 @@
+-- Record metadata, labels are filtered with containment which the GIN index supports
+ALTER TABLE record ADD COLUMN IF NOT EXISTS labels JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE record ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+ALTER TABLE record ADD COLUMN IF NOT EXISTS owner VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE record ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_record_labels ON record USING GIN (labels);
+CREATE INDEX IF NOT EXISTS idx_record_owner ON record (owner, id) WHERE deleted_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_record_alias_of;
+ALTER TABLE record DROP COLUMN IF EXISTS alias_of;
\ No newline at end of file
@@ -0,0 +1,4 @@
+-- Aliases answer like the record of another domain, see Record.AliasOf
+ALTER TABLE record ADD COLUMN IF NOT EXISTS alias_of VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_record_alias_of ON record (alias_of, id) WHERE alias_of <> '' AND deleted_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_record_audit_tenant_domain;
+CREATE INDEX IF NOT EXISTS idx_record_audit_domain ON record_audit (domain, id);
+
+DROP INDEX IF EXISTS uniq_record_tenant_domain_window_live;
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_record_domain_window_live ON record (
+    domain,
+    COALESCE(valid_from, '-infinity'::TIMESTAMP WITH TIME ZONE),
+    COALESCE(valid_until, 'infinity'::TIMESTAMP WITH TIME ZONE)
+) WHERE deleted_at IS NULL;
+
+ALTER TABLE record_audit DROP COLUMN IF EXISTS tenant;
+ALTER TABLE record DROP COLUMN IF EXISTS tenant;
\ No newline at end of file
@@ -0,0 +1,14 @@
+-- Tenants namespace records, a domain is unique per tenant, see Record.Tenant
+ALTER TABLE record ADD COLUMN IF NOT EXISTS tenant VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE record_audit ADD COLUMN IF NOT EXISTS tenant VARCHAR(64) NOT NULL DEFAULT '';
+
+DROP INDEX IF EXISTS uniq_record_domain_window_live;
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_record_tenant_domain_window_live ON record (
+    tenant,
+    domain,
+    COALESCE(valid_from, '-infinity'::TIMESTAMP WITH TIME ZONE),
+    COALESCE(valid_until, 'infinity'::TIMESTAMP WITH TIME ZONE)
+) WHERE deleted_at IS NULL;
+
+DROP INDEX IF EXISTS idx_record_audit_domain;
+CREATE INDEX IF NOT EXISTS idx_record_audit_tenant_domain ON record_audit (tenant, domain, id);
\ No newline at end of file
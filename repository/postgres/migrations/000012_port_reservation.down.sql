@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_record_target_port;
+DROP TABLE IF EXISTS port_reservation;
\ No newline at end of file
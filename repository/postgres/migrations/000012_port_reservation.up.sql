@@ -0,0 +1,13 @@
+-- Ports are a resource of the host, so a port is reserved once per target across tenants
+CREATE TABLE IF NOT EXISTS port_reservation (
+    id BIGSERIAL PRIMARY KEY,
+    tenant VARCHAR(64) NOT NULL DEFAULT '',
+    instance_id BIGINT NOT NULL,
+    target VARCHAR(255) NOT NULL,
+    port INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_port_reservation_target_port ON port_reservation (target, port);
+CREATE INDEX IF NOT EXISTS idx_port_reservation_instance ON port_reservation (tenant, instance_id);
+CREATE INDEX IF NOT EXISTS idx_record_target_port ON record (target, port) WHERE deleted_at IS NULL;
\ No newline at end of file
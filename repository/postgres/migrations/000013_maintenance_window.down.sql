@@ -0,0 +1 @@
+DROP TABLE IF EXISTS maintenance_window;
\ No newline at end of file
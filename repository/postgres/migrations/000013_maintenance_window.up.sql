@@ -0,0 +1,15 @@
+-- Maintenance windows redirect the queries of a scope, see types.Maintenance
+CREATE TABLE IF NOT EXISTS maintenance_window (
+    id BIGSERIAL PRIMARY KEY,
+    tenant VARCHAR(64) NOT NULL DEFAULT '',
+    scope VARCHAR(16) NOT NULL,
+    instance_id BIGINT NOT NULL DEFAULT 0,
+    domain VARCHAR(255) NOT NULL DEFAULT '',
+    target VARCHAR(255) NOT NULL DEFAULT '',
+    port INTEGER NOT NULL DEFAULT 0,
+    valid_from TIMESTAMP WITH TIME ZONE,
+    valid_until TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_maintenance_window_tenant ON maintenance_window (tenant, id);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_record_audit_record_id;
\ No newline at end of file
@@ -0,0 +1,5 @@
+-- Purge removes the audit entries of the records it removes, see RecordRepository.Purge.
+-- Entries left behind by earlier purges are dropped here
+DELETE FROM record_audit WHERE NOT EXISTS (SELECT 1 FROM record WHERE record.id = record_audit.record_id);
+
+CREATE INDEX IF NOT EXISTS idx_record_audit_record_id ON record_audit (record_id);
\ No newline at end of file
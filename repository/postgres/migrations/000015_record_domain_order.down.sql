@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_record_domain_order;
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- List sorts domains by byte order like the file repository, whatever the database collation
+CREATE INDEX IF NOT EXISTS idx_record_domain_order ON record (tenant, (domain COLLATE "C"), id) WHERE deleted_at IS NULL;
\ No newline at end of file
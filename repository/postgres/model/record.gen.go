@@ -14,14 +14,24 @@ const TableNameRecord = "record"
 
 // Record mapped from table <record>
 type Record struct {
-	ID         int64          `gorm:"column:id;primaryKey;autoIncrement:true" json:"id"`
-	InstanceID int64          `gorm:"column:instance_id;not null" json:"instance_id"`
-	Domain     string         `gorm:"column:domain;not null" json:"domain"`
-	Target     string         `gorm:"column:target;not null" json:"target"`
-	Port       int32          `gorm:"column:port" json:"port"`
-	CreatedAt  time.Time      `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt  time.Time      `gorm:"column:updated_at;default:CURRENT_TIMESTAMP" json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `gorm:"column:deleted_at" json:"deleted_at"`
+	ID          int64          `gorm:"column:id;primaryKey;autoIncrement:true" json:"id"`
+	InstanceID  int64          `gorm:"column:instance_id;not null" json:"instance_id"`
+	Domain      string         `gorm:"column:domain;not null" json:"domain"`
+	Target      string         `gorm:"column:target;not null" json:"target"`
+	Port        int32          `gorm:"column:port" json:"port"`
+	CreatedAt   time.Time      `gorm:"column:created_at;default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"column:updated_at;default:CURRENT_TIMESTAMP" json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"column:deleted_at" json:"deleted_at"`
+	ExpiresAt   *time.Time     `gorm:"column:expires_at" json:"expires_at"`
+	ValidFrom   *time.Time     `gorm:"column:valid_from" json:"valid_from"`
+	ValidUntil  *time.Time     `gorm:"column:valid_until" json:"valid_until"`
+	Version     int64          `gorm:"column:version;not null;default:1" json:"version"`
+	Labels      string         `gorm:"column:labels;not null;default:'{}'" json:"labels"`
+	Description string         `gorm:"column:description;not null" json:"description"`
+	Owner       string         `gorm:"column:owner;not null" json:"owner"`
+	Disabled    bool           `gorm:"column:disabled;not null" json:"disabled"`
+	AliasOf     string         `gorm:"column:alias_of;not null" json:"alias_of"`
+	Tenant      string         `gorm:"column:tenant;not null" json:"tenant"`
 }
 
 // TableName Record's table name
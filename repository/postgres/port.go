@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/honeybbq/tsdns-go/repository/postgres/model"
+	"github.com/honeybbq/tsdns-go/types"
+	"gorm.io/gorm"
+)
+
+// maxReserveAttempts bounds how often a reservation losing a port to a concurrent one is retried
+const maxReserveAttempts = 5
+
+// ReservePort reserves the lowest free port of target within ports for an instance
+// uniq_port_reservation_target_port keeps concurrent reservations from taking the same port
+func (p *repository) ReservePort(ctx context.Context, target string, ports types.PortRange, instanceID int64) (int32, error) {
+	if err := ports.Validate(); err != nil {
+		return 0, err
+	}
+
+	for attempt := 1; ; attempt++ {
+		port, err := p.reservePort(ctx, target, ports, instanceID)
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			if attempt < maxReserveAttempts {
+				continue
+			}
+			return 0, fmt.Errorf("%w: ports of %s are reserved concurrently", types.ErrConflict, target)
+		}
+		if err != nil {
+			return 0, p.mapError(err)
+		}
+		return port, nil
+	}
+}
+
+// reservePort reserves the lowest port of the range neither reserved nor used by a live record
+func (p *repository) reservePort(ctx context.Context, target string, ports types.PortRange, instanceID int64) (int32, error) {
+	pr := p.q.PortReservation
+	var reserved []int32
+	err := pr.WithContext(ctx).Where(pr.Target.Eq(target), pr.Port.Between(ports.Min, ports.Max)).Pluck(pr.Port, &reserved)
+	if err != nil {
+		return 0, err
+	}
+
+	// ports are shared by all tenants of the host, so the records are not scoped to the tenant
+	r := p.q.Record
+	var used []int32
+	err = r.WithContext(ctx).Where(r.Target.Eq(target), r.Port.Between(ports.Min, ports.Max)).Pluck(r.Port, &used)
+	if err != nil {
+		return 0, err
+	}
+
+	taken := make(map[int32]bool, len(reserved)+len(used))
+	for _, port := range append(reserved, used...) {
+		taken[port] = true
+	}
+	for port := ports.Min; port <= ports.Max; port++ {
+		if taken[port] {
+			continue
+		}
+		err = pr.WithContext(ctx).Create(&model.PortReservation{
+			Tenant:     tenantOf(ctx),
+			InstanceID: instanceID,
+			Target:     target,
+			Port:       port,
+		})
+		if err != nil {
+			return 0, err
+		}
+		return port, nil
+	}
+	return 0, fmt.Errorf("%w: %s %s", types.ErrNoFreePort, target, ports)
+}
+
+// ReleasePorts releases the ports reserved for an instance
+func (p *repository) ReleasePorts(ctx context.Context, instanceID int64) (int64, error) {
+	pr := p.q.PortReservation
+	info, err := pr.WithContext(ctx).Where(pr.Tenant.Eq(tenantOf(ctx)), pr.InstanceID.Eq(instanceID)).Delete()
+	if err != nil {
+		return 0, p.mapError(err)
+	}
+	return info.RowsAffected, nil
+}
@@ -1,6 +1,12 @@
 package postgres
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/honeybbq/tsdns-go/repository/postgres/model"
@@ -8,19 +14,23 @@ import (
 	"github.com/honeybbq/tsdns-go/types"
 
 	"gorm.io/driver/postgres"
+	"gorm.io/gen"
+	"gorm.io/gen/field"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type repository struct {
-	db *gorm.DB
-	q  *query.Query
+	db     *gorm.DB
+	q      *query.Query
+	closed atomic.Bool
 }
 
 // NewRepository creates a new PostgreSQL storage implementation
 //
 // dsn is the PostgreSQL connection string
 func NewRepository(dsn string) (types.RecordRepository, error) {
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		return nil, err
 	}
@@ -57,14 +67,24 @@ func (p *repository) toRecord(m *model.Record) *types.Record {
 	}
 
 	return &types.Record{
-		ID:         m.ID,
-		InstanceID: m.InstanceID,
-		Domain:     m.Domain,
-		Target:     m.Target,
-		Port:       m.Port,
-		CreatedAt:  m.CreatedAt,
-		UpdatedAt:  m.UpdatedAt,
-		DeletedAt:  deletedAt,
+		ID:          m.ID,
+		InstanceID:  m.InstanceID,
+		Domain:      m.Domain,
+		Target:      m.Target,
+		Port:        m.Port,
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+		DeletedAt:   deletedAt,
+		ExpiresAt:   m.ExpiresAt,
+		ValidFrom:   m.ValidFrom,
+		ValidUntil:  m.ValidUntil,
+		Version:     m.Version,
+		Labels:      decodeLabels(m.Labels),
+		Description: m.Description,
+		Owner:       m.Owner,
+		Disabled:    m.Disabled,
+		AliasOf:     m.AliasOf,
+		Tenant:      m.Tenant,
 	}
 }
 
@@ -83,59 +103,669 @@ func (p *repository) toModel(r *types.Record) *model.Record {
 	}
 
 	return &model.Record{
-		ID:         r.ID,
-		InstanceID: r.InstanceID,
-		Domain:     r.Domain,
-		Target:     r.Target,
-		Port:       r.Port,
-		CreatedAt:  r.CreatedAt,
-		UpdatedAt:  r.UpdatedAt,
-		DeletedAt:  deletedAt,
+		ID:          r.ID,
+		InstanceID:  r.InstanceID,
+		Domain:      r.Domain,
+		Target:      r.Target,
+		Port:        r.Port,
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+		DeletedAt:   deletedAt,
+		ExpiresAt:   r.ExpiresAt,
+		ValidFrom:   r.ValidFrom,
+		ValidUntil:  r.ValidUntil,
+		Version:     r.Version,
+		Labels:      encodeLabels(r.Labels),
+		Description: r.Description,
+		Owner:       r.Owner,
+		Disabled:    r.Disabled,
+		AliasOf:     r.AliasOf,
+		Tenant:      r.Tenant,
 	}
 }
 
-func (p *repository) Find() ([]*types.Record, error) {
-	models, err := p.q.Record.Find()
+// encodeLabels encodes record labels for the JSONB labels column
+func encodeLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return "{}"
+	}
+	data, _ := json.Marshal(labels)
+	return string(data)
+}
+
+// decodeLabels decodes the JSONB labels column, labels that are not strings are dropped
+func decodeLabels(value string) map[string]string {
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(value), &raw); err != nil || len(raw) == 0 {
+		return nil
+	}
+	labels := make(map[string]string, len(raw))
+	for key, v := range raw {
+		if s, ok := v.(string); ok {
+			labels[key] = s
+		}
+	}
+	return labels
+}
+
+// transaction runs fc in a transaction bound to ctx
+func (p *repository) transaction(ctx context.Context, fc func(tx *query.Query) error) error {
+	return query.Use(p.db.WithContext(ctx)).Transaction(fc)
+}
+
+// mapError translates gorm errors to the errors declared in the types package
+func (p *repository) mapError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case p.closed.Load():
+		return types.ErrClosed
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return err
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return types.ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return types.ErrDomainExists
+	default:
+		return err
+	}
+}
+
+// tenantOf returns the tenant the operation of ctx is scoped to
+func tenantOf(ctx context.Context) string {
+	tenant, _ := types.TenantFrom(ctx)
+	return tenant
+}
+
+// records starts a query on the DNS records of the tenant of ctx using the given query
+func (p *repository) records(ctx context.Context, tx *query.Query) query.IRecordDo {
+	return tx.Record.WithContext(ctx).Where(tx.Record.Tenant.Eq(tenantOf(ctx)))
+}
+
+// Find retrieves all live DNS records
+func (p *repository) Find(ctx context.Context) ([]*types.Record, error) {
+	return p.findAll(p.records(ctx, p.q))
+}
+
+// List retrieves a page of live DNS records matching the options
+// Filters and the cursor are applied in SQL, so pages are served from the indexes
+func (p *repository) List(ctx context.Context, opts types.ListOptions) (*types.RecordPage, error) {
+	cursor, err := opts.Validate()
 	if err != nil {
 		return nil, err
 	}
 
+	r := p.q.Record
+	do := p.records(ctx, p.q)
+	if opts.InstanceID != 0 {
+		do = do.Where(r.InstanceID.Eq(opts.InstanceID))
+	}
+	if opts.DomainPrefix != "" {
+		do = do.Where(r.Domain.Like(escapeLike(opts.DomainPrefix) + "%"))
+	}
+	if opts.DomainContains != "" {
+		do = do.Where(r.Domain.Like("%" + escapeLike(opts.DomainContains) + "%"))
+	}
+	if opts.Target != "" {
+		do = do.Where(r.Target.Eq(opts.Target))
+	}
+	if opts.Owner != "" {
+		do = do.Where(r.Owner.Eq(opts.Owner))
+	}
+	if opts.AliasOf != "" {
+		do = do.Where(r.AliasOf.Eq(opts.AliasOf))
+	}
+	if len(opts.Labels) > 0 {
+		// containment is answered by the GIN index on labels
+		do = do.Where(field.NewUnsafeFieldRaw("labels @> ?::jsonb", encodeLabels(opts.Labels)))
+	}
+	if opts.Disabled != nil {
+		do = do.Where(r.Disabled.Is(*opts.Disabled))
+	}
+
+	// keyset pagination, records after the cursor sort after it by key then ID
+	orders := []field.Expr{r.ID}
+	if opts.Descending {
+		orders = []field.Expr{r.ID.Desc()}
+	}
+	switch opts.Sort {
+	case types.SortByDomain:
+		// domains sort by byte order like in the file repository, whatever the database collation
+		domain := field.NewUnsafeFieldRaw(`domain COLLATE "C"`)
+		if opts.Descending {
+			orders = append([]field.Expr{domain.Desc()}, orders...)
+		} else {
+			orders = append([]field.Expr{domain}, orders...)
+		}
+		if cursor != nil {
+			after := field.NewUnsafeFieldRaw(`domain COLLATE "C" > ?`, cursor.Domain)
+			if opts.Descending {
+				after = field.NewUnsafeFieldRaw(`domain COLLATE "C" < ?`, cursor.Domain)
+			}
+			do = do.Where(r.Where(after).Or(r.Domain.Eq(cursor.Domain), idAfter(r.ID, cursor, opts.Descending)))
+		}
+	case types.SortByCreatedAt:
+		if opts.Descending {
+			orders = append([]field.Expr{r.CreatedAt.Desc()}, orders...)
+		} else {
+			orders = append([]field.Expr{r.CreatedAt}, orders...)
+		}
+		if cursor != nil {
+			after := r.CreatedAt.Gt(cursor.CreatedAt)
+			if opts.Descending {
+				after = r.CreatedAt.Lt(cursor.CreatedAt)
+			}
+			do = do.Where(r.Where(after).Or(r.CreatedAt.Eq(cursor.CreatedAt), idAfter(r.ID, cursor, opts.Descending)))
+		}
+	default:
+		if cursor != nil {
+			do = do.Where(idAfter(r.ID, cursor, opts.Descending))
+		}
+	}
+	do = do.Order(orders...)
+	if opts.Limit > 0 {
+		do = do.Limit(opts.Limit + 1)
+	}
+
+	records, err := p.findAll(do)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewRecordPage(records, opts.Limit), nil
+}
+
+// idAfter matches the IDs listed after the cursor
+func idAfter(id field.Int64, cursor *types.Cursor, descending bool) field.Expr {
+	if descending {
+		return id.Lt(cursor.ID)
+	}
+	return id.Gt(cursor.ID)
+}
+
+// escapeLike escapes the LIKE wildcards in s
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// FindByDomain finds the record currently answering for a domain name
+func (p *repository) FindByDomain(ctx context.Context, domain string) (*types.Record, error) {
+	models, err := p.records(ctx, p.q).Where(p.q.Record.Domain.Eq(domain)).Find()
+	if err != nil {
+		return nil, p.mapError(err)
+	}
+
 	records := make([]*types.Record, len(models))
 	for i, m := range models {
 		records[i] = p.toRecord(m)
 	}
-	return records, nil
+
+	record := types.Active(records, time.Now())
+	if record == nil {
+		return nil, types.ErrNotFound
+	}
+	return record, nil
 }
 
-// FindByDomain finds a record by domain name
-func (p *repository) FindByDomain(domain string) (*types.Record, error) {
-	m, err := p.q.Record.Where(p.q.Record.Domain.Eq(domain)).First()
+// FindByTarget retrieves the DNS records pointing to a host ordered by ID
+// The lookup is served by idx_record_target
+func (p *repository) FindByTarget(ctx context.Context, target string, includeDeleted bool) ([]*types.Record, error) {
+	r := p.q.Record
+	do := p.records(ctx, p.q)
+	if includeDeleted {
+		do = do.Unscoped()
+	}
+	return p.findAll(do.Where(r.Target.Eq(target)).Order(r.ID))
+}
+
+// FindByInstanceID retrieves the DNS records of an instance ordered by ID
+func (p *repository) FindByInstanceID(ctx context.Context, instanceID int64, includeDeleted bool) ([]*types.Record, error) {
+	r := p.q.Record
+	do := p.records(ctx, p.q)
+	if includeDeleted {
+		do = do.Unscoped()
+	}
+	return p.findAll(do.Where(r.InstanceID.Eq(instanceID)).Order(r.ID))
+}
+
+// findAll runs the query and converts the resulting models
+func (p *repository) findAll(do query.IRecordDo) ([]*types.Record, error) {
+	models, err := do.Find()
 	if err != nil {
-		return nil, err
+		return nil, p.mapError(err)
 	}
-	return p.toRecord(m), nil
+
+	records := make([]*types.Record, len(models))
+	for i, m := range models {
+		records[i] = p.toRecord(m)
+	}
+	return records, nil
 }
 
 // Create creates a new DNS record
-func (p *repository) Create(record *types.Record) error {
+func (p *repository) Create(ctx context.Context, record *types.Record) error {
+	return p.mapError(p.transaction(ctx, func(tx *query.Query) error {
+		return p.create(ctx, tx, record)
+	}))
+}
+
+// create inserts a new DNS record using the given query
+func (p *repository) create(ctx context.Context, tx *query.Query, record *types.Record) error {
+	if err := record.Validate(); err != nil {
+		return err
+	}
+	if err := types.AssignTenant(record, tenantOf(ctx)); err != nil {
+		return err
+	}
+
 	m := p.toModel(record)
-	return p.q.Record.Create(m)
+	m.ID = 0
+	m.Version = 1
+	if err := tx.Record.WithContext(ctx).Create(m); err != nil {
+		return p.mapError(err)
+	}
+	*record = *p.toRecord(m)
+	return p.audit(ctx, tx, types.AuditCreate, nil, []*types.Record{record})
 }
 
-// Delete removes a DNS record by domain
-func (p *repository) Delete(domain string) error {
-	_, err := p.q.Record.Where(p.q.Record.Domain.Eq(domain)).Delete()
-	return err
+// windowConds matches records of the same domain and validity window as r
+func (p *repository) windowConds(tx *query.Query, r *types.Record) []gen.Condition {
+	conds := []gen.Condition{tx.Record.Domain.Eq(r.Domain)}
+	if r.ValidFrom == nil {
+		conds = append(conds, tx.Record.ValidFrom.IsNull())
+	} else {
+		conds = append(conds, tx.Record.ValidFrom.Eq(*r.ValidFrom))
+	}
+	if r.ValidUntil == nil {
+		conds = append(conds, tx.Record.ValidUntil.IsNull())
+	} else {
+		conds = append(conds, tx.Record.ValidUntil.Eq(*r.ValidUntil))
+	}
+	return conds
+}
+
+// update writes the mutable fields of record to the existing row
+// The row is only written if it still has the version the record expects
+func (p *repository) update(ctx context.Context, tx *query.Query, existing *model.Record, record *types.Record) error {
+	before := p.toRecord(existing)
+	conflict := &types.VersionConflictError{
+		ID:       existing.ID,
+		Domain:   existing.Domain,
+		Expected: record.Version,
+		Actual:   existing.Version,
+	}
+	expected := record.Version
+	if expected == 0 {
+		expected = existing.Version
+	}
+
+	existing.InstanceID = record.InstanceID
+	existing.Target = record.Target
+	existing.Port = record.Port
+	existing.ExpiresAt = record.ExpiresAt
+	existing.ValidFrom = record.ValidFrom
+	existing.ValidUntil = record.ValidUntil
+	existing.Labels = encodeLabels(record.Labels)
+	existing.Description = record.Description
+	existing.Owner = record.Owner
+	existing.Disabled = record.Disabled
+	existing.AliasOf = record.AliasOf
+	existing.UpdatedAt = time.Now()
+	existing.Version = expected + 1
+
+	updated := p.toRecord(existing)
+	if err := updated.Validate(); err != nil {
+		return err
+	}
+
+	r := tx.Record
+	info, err := r.WithContext(ctx).Where(r.ID.Eq(existing.ID), r.Version.Eq(expected)).
+		Select(r.InstanceID, r.Target, r.Port, r.ExpiresAt, r.ValidFrom, r.ValidUntil,
+			r.Labels, r.Description, r.Owner, r.Disabled, r.AliasOf, r.UpdatedAt, r.Version).
+		Updates(existing)
+	if err != nil {
+		return p.mapError(err)
+	}
+	if info.RowsAffected == 0 {
+		return conflict
+	}
+
+	*record = *updated
+	return p.audit(ctx, tx, types.AuditUpdate, []*types.Record{before}, []*types.Record{updated})
+}
+
+// Update changes the instance, target, port, lease, validity window and metadata of a live DNS record
+// The record is identified by its ID, or by domain and validity window if ID is 0
+func (p *repository) Update(ctx context.Context, record *types.Record) error {
+	if record == nil {
+		return record.Validate()
+	}
+
+	return p.mapError(p.transaction(ctx, func(tx *query.Query) error {
+		return p.updateRecord(ctx, tx, record)
+	}))
+}
+
+// updateRecord locks the existing row of record and updates it using the given query
+func (p *repository) updateRecord(ctx context.Context, tx *query.Query, record *types.Record) error {
+	if err := types.AssignTenant(record, tenantOf(ctx)); err != nil {
+		return err
+	}
+
+	do := p.records(ctx, tx).Clauses(clause.Locking{Strength: "UPDATE"})
+	if record.ID != 0 {
+		do = do.Where(tx.Record.ID.Eq(record.ID))
+	} else {
+		do = do.Where(p.windowConds(tx, record)...)
+	}
+
+	existing, err := do.First()
+	if err != nil {
+		return p.mapError(err)
+	}
+	return p.update(ctx, tx, existing, record)
+}
+
+// Upsert updates the live DNS record with the same domain and validity window, or creates it
+func (p *repository) Upsert(ctx context.Context, record *types.Record) error {
+	if err := record.Validate(); err != nil {
+		return err
+	}
+
+	return p.mapError(p.transaction(ctx, func(tx *query.Query) error {
+		return p.upsert(ctx, tx, record)
+	}))
+}
+
+// upsert updates or creates record using the given query
+func (p *repository) upsert(ctx context.Context, tx *query.Query, record *types.Record) error {
+	if err := record.Validate(); err != nil {
+		return err
+	}
+	if err := types.AssignTenant(record, tenantOf(ctx)); err != nil {
+		return err
+	}
+
+	existing, err := p.records(ctx, tx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where(p.windowConds(tx, record)...).
+		First()
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return p.create(ctx, tx, record)
+	}
+	if err != nil {
+		return p.mapError(err)
+	}
+	return p.update(ctx, tx, existing, record)
+}
+
+// Retarget points the live DNS records of the from endpoint matching the options to the to endpoint
+// The matching rows are locked and rewritten in a single transaction
+func (p *repository) Retarget(ctx context.Context, from, to types.Endpoint, opts types.RetargetOptions) ([]types.RecordDiff, error) {
+	if err := types.ValidateRetarget(from, to, opts); err != nil {
+		return nil, err
+	}
+
+	var diffs []types.RecordDiff
+	err := p.transaction(ctx, func(tx *query.Query) error {
+		r := tx.Record
+		do := p.records(ctx, tx).Where(r.Target.Eq(from.Target))
+		if from.Port != 0 {
+			do = do.Where(r.Port.Eq(from.Port))
+		}
+		if opts.InstanceID != 0 {
+			do = do.Where(r.InstanceID.Eq(opts.InstanceID))
+		}
+		if opts.DomainPrefix != "" {
+			do = do.Where(r.Domain.Like(escapeLike(opts.DomainPrefix) + "%"))
+		}
+		if opts.PortMin != 0 {
+			do = do.Where(r.Port.Gte(opts.PortMin))
+		}
+		if opts.PortMax != 0 {
+			do = do.Where(r.Port.Lte(opts.PortMax))
+		}
+
+		models, err := do.Clauses(clause.Locking{Strength: "UPDATE"}).Order(r.ID).Find()
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		diffs = make([]types.RecordDiff, 0, len(models))
+		for _, m := range models {
+			before := p.toRecord(m)
+			after, err := types.RetargetRecord(before, to, opts)
+			if err != nil {
+				return err
+			}
+			diffs = append(diffs, types.RecordDiff{Before: before, After: after})
+			if opts.DryRun {
+				continue
+			}
+
+			after.UpdatedAt = now
+			after.Version++
+			_, err = r.WithContext(ctx).Where(r.ID.Eq(m.ID)).
+				UpdateSimple(r.Target.Value(after.Target), r.Port.Value(after.Port), r.UpdatedAt.Value(now), r.Version.Add(1))
+			if err != nil {
+				return err
+			}
+		}
+		if opts.DryRun {
+			return nil
+		}
+
+		before := make([]*types.Record, len(diffs))
+		after := make([]*types.Record, len(diffs))
+		for i, diff := range diffs {
+			before[i], after[i] = diff.Before, diff.After
+		}
+		return p.audit(ctx, tx, types.AuditUpdate, before, after)
+	})
+	if err != nil {
+		return nil, p.mapError(err)
+	}
+	return diffs, nil
+}
+
+// Delete removes all DNS records of a domain, including scheduled ones
+func (p *repository) Delete(ctx context.Context, domain string) error {
+	return p.mapError(p.transaction(ctx, func(tx *query.Query) error {
+		return p.delete(ctx, tx, domain)
+	}))
+}
+
+// delete removes all DNS records of a domain using the given query
+func (p *repository) delete(ctx context.Context, tx *query.Query, domain string) error {
+	deleted, err := p.deleteRecords(ctx, tx, p.records(ctx, tx).Where(tx.Record.Domain.Eq(domain)))
+	if err != nil {
+		return p.mapError(err)
+	}
+	if len(deleted) == 0 {
+		return types.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteByID removes a single DNS record
+func (p *repository) DeleteByID(ctx context.Context, id int64) error {
+	return p.mapError(p.transaction(ctx, func(tx *query.Query) error {
+		return p.deleteByID(ctx, tx, id)
+	}))
+}
+
+// deleteByID removes a single DNS record using the given query
+func (p *repository) deleteByID(ctx context.Context, tx *query.Query, id int64) error {
+	deleted, err := p.deleteRecords(ctx, tx, p.records(ctx, tx).Where(tx.Record.ID.Eq(id)))
+	if err != nil {
+		return p.mapError(err)
+	}
+	if len(deleted) == 0 {
+		return types.ErrNotFound
+	}
+	return nil
 }
 
 // DeleteByInstanceID removes all records for a specific instance
-func (p *repository) DeleteByInstanceID(instanceID int64) error {
-	_, err := p.q.Record.Where(p.q.Record.InstanceID.Eq(instanceID)).Delete()
-	return err
+func (p *repository) DeleteByInstanceID(ctx context.Context, instanceID int64) error {
+	return p.mapError(p.transaction(ctx, func(tx *query.Query) error {
+		return p.deleteByInstanceID(ctx, tx, instanceID)
+	}))
+}
+
+// deleteByInstanceID removes all records for a specific instance using the given query
+func (p *repository) deleteByInstanceID(ctx context.Context, tx *query.Query, instanceID int64) error {
+	_, err := p.deleteRecords(ctx, tx, p.records(ctx, tx).Where(tx.Record.InstanceID.Eq(instanceID)))
+	return p.mapError(err)
+}
+
+// Apply applies a batch of changes in order in a single transaction
+// A failing change rolls back the changes applied before it
+func (p *repository) Apply(ctx context.Context, changes []types.Change) error {
+	err := p.transaction(ctx, func(tx *query.Query) error {
+		for i, change := range changes {
+			if err := p.applyChange(ctx, tx, change); err != nil {
+				return fmt.Errorf("change %d: %w", i, p.mapError(err))
+			}
+		}
+		return nil
+	})
+	return p.mapError(err)
+}
+
+// applyChange applies a single change using the given query
+func (p *repository) applyChange(ctx context.Context, tx *query.Query, change types.Change) error {
+	switch change.Op {
+	case types.ChangeCreate:
+		return p.create(ctx, tx, change.Record)
+	case types.ChangeUpdate:
+		return p.updateRecord(ctx, tx, change.Record)
+	case types.ChangeUpsert:
+		return p.upsert(ctx, tx, change.Record)
+	case types.ChangeDelete:
+		return p.delete(ctx, tx, change.Domain)
+	case types.ChangeDeleteID:
+		return p.deleteByID(ctx, tx, change.ID)
+	case types.ChangeDeleteInstance:
+		return p.deleteByInstanceID(ctx, tx, change.InstanceID)
+	default:
+		return fmt.Errorf("unknown change operation %d", change.Op)
+	}
+}
+
+// Renew sets the lease expiry of all records of a domain
+func (p *repository) Renew(ctx context.Context, domain string, expiresAt time.Time) error {
+	info, err := p.records(ctx, p.q).Where(p.q.Record.Domain.Eq(domain)).Update(p.q.Record.ExpiresAt, expiresAt)
+	if err != nil {
+		return p.mapError(err)
+	}
+	if info.RowsAffected == 0 {
+		return types.ErrNotFound
+	}
+	return nil
+}
+
+// RenewByInstanceID sets the lease expiry of all records for a specific instance
+func (p *repository) RenewByInstanceID(ctx context.Context, instanceID int64, expiresAt time.Time) error {
+	_, err := p.records(ctx, p.q).Where(p.q.Record.InstanceID.Eq(instanceID)).Update(p.q.Record.ExpiresAt, expiresAt)
+	return p.mapError(err)
+}
+
+// DeleteExpired removes all records whose lease or validity window ended at or before the given time
+func (p *repository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	var count int64
+	err := p.transaction(ctx, func(tx *query.Query) error {
+		r := tx.Record
+		deleted, err := p.deleteRecords(ctx, tx, p.records(ctx, tx).Where(r.Where(r.ExpiresAt.Lte(now)).Or(r.ValidUntil.Lte(now))))
+		count = int64(len(deleted))
+		return err
+	})
+	if err != nil {
+		return 0, p.mapError(err)
+	}
+	return count, nil
+}
+
+// ListDeleted retrieves all soft-deleted DNS records
+func (p *repository) ListDeleted(ctx context.Context) ([]*types.Record, error) {
+	return p.findAll(p.records(ctx, p.q).Unscoped().Where(p.q.Record.DeletedAt.IsNotNull()))
+}
+
+// Restore undeletes the DNS records of a domain removed by its most recent deletion
+func (p *repository) Restore(ctx context.Context, domain string) ([]*types.Record, error) {
+	var records []*types.Record
+	err := p.transaction(ctx, func(tx *query.Query) error {
+		r := tx.Record
+		latest, err := p.records(ctx, tx).Unscoped().
+			Where(r.Domain.Eq(domain), r.DeletedAt.IsNotNull()).
+			Order(r.DeletedAt.Desc()).
+			First()
+		if err != nil {
+			return err
+		}
+
+		deleted := p.records(ctx, tx).Unscoped().Where(r.Domain.Eq(domain), r.DeletedAt.Eq(latest.DeletedAt))
+		models, err := deleted.Clauses(clause.Locking{Strength: "UPDATE"}).Find()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if _, err = deleted.UpdateSimple(r.DeletedAt.Value(gorm.DeletedAt{}), r.UpdatedAt.Value(now)); err != nil {
+			return err
+		}
+
+		before := make([]*types.Record, len(models))
+		records = make([]*types.Record, len(models))
+		for i, m := range models {
+			before[i] = p.toRecord(m)
+			m.DeletedAt, m.UpdatedAt = gorm.DeletedAt{}, now
+			records[i] = p.toRecord(m)
+		}
+		return p.audit(ctx, tx, types.AuditRestore, before, records)
+	})
+	if err != nil {
+		return nil, p.mapError(err)
+	}
+	return records, nil
+}
+
+// Purge permanently removes DNS records soft-deleted before the given time and their audit entries
+func (p *repository) Purge(ctx context.Context, olderThan time.Time) (int64, error) {
+	var count int64
+	err := p.transaction(ctx, func(tx *query.Query) error {
+		r := tx.Record
+		models, err := p.records(ctx, tx).Unscoped().Select(r.ID).
+			Where(r.DeletedAt.IsNotNull(), r.DeletedAt.Lt(gorm.DeletedAt{Time: olderThan, Valid: true})).
+			Clauses(clause.Locking{Strength: "UPDATE"}).Find()
+		if err != nil || len(models) == 0 {
+			return err
+		}
+		ids := make([]int64, len(models))
+		for i, m := range models {
+			ids[i] = m.ID
+		}
+
+		a := tx.RecordAudit
+		if _, err = a.WithContext(ctx).Where(a.Tenant.Eq(tenantOf(ctx)), a.RecordID.In(ids...)).Delete(); err != nil {
+			return err
+		}
+		info, err := r.WithContext(ctx).Unscoped().Where(r.ID.In(ids...)).Delete()
+		count = info.RowsAffected
+		return err
+	})
+	if err != nil {
+		return 0, p.mapError(err)
+	}
+	return count, nil
 }
 
 // Close closes the storage connection
+// Closing an already closed repository is a no-op
 func (p *repository) Close() error {
+	if p.closed.Swap(true) {
+		return nil
+	}
+
 	sqlDB, err := p.db.DB()
 	if err != nil {
 		return err
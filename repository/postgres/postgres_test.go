@@ -0,0 +1,43 @@
+package postgres_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/honeybbq/tsdns-go/repository/postgres"
+	"github.com/honeybbq/tsdns-go/repository/postgres/migrations"
+	"github.com/honeybbq/tsdns-go/repository/repotest"
+	"github.com/honeybbq/tsdns-go/types"
+
+	driver "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// dsnEnv names the variable holding the DSN of a scratch database, its tables are emptied by the tests
+const dsnEnv = "TSDNS_TEST_POSTGRES_DSN"
+
+func TestRepository(t *testing.T) {
+	dsn := os.Getenv(dsnEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", dsnEnv)
+	}
+	if err := migrations.AutoMigrate(dsn); err != nil {
+		t.Fatalf("AutoMigrate error: %v", err)
+	}
+	db, err := gorm.Open(driver.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+
+	repotest.Run(t, func(t *testing.T) types.RecordRepository {
+		err := db.Exec("TRUNCATE record, record_audit, port_reservation, maintenance_window RESTART IDENTITY").Error
+		if err != nil {
+			t.Fatalf("truncate tables: %v", err)
+		}
+		repo, err := postgres.NewRepository(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	})
+}
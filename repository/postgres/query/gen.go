@@ -16,34 +16,49 @@ import (
 )
 
 var (
-	Q      = new(Query)
-	Record *record
+	Q                 = new(Query)
+	MaintenanceWindow *maintenanceWindow
+	PortReservation   *portReservation
+	Record            *record
+	RecordAudit       *recordAudit
 )
 
 func SetDefault(db *gorm.DB, opts ...gen.DOOption) {
 	*Q = *Use(db, opts...)
+	MaintenanceWindow = &Q.MaintenanceWindow
+	PortReservation = &Q.PortReservation
 	Record = &Q.Record
+	RecordAudit = &Q.RecordAudit
 }
 
 func Use(db *gorm.DB, opts ...gen.DOOption) *Query {
 	return &Query{
-		db:     db,
-		Record: newRecord(db, opts...),
+		db:                db,
+		MaintenanceWindow: newMaintenanceWindow(db, opts...),
+		PortReservation:   newPortReservation(db, opts...),
+		Record:            newRecord(db, opts...),
+		RecordAudit:       newRecordAudit(db, opts...),
 	}
 }
 
 type Query struct {
 	db *gorm.DB
 
-	Record record
+	MaintenanceWindow maintenanceWindow
+	PortReservation   portReservation
+	Record            record
+	RecordAudit       recordAudit
 }
 
 func (q *Query) Available() bool { return q.db != nil }
 
 func (q *Query) clone(db *gorm.DB) *Query {
 	return &Query{
-		db:     db,
-		Record: q.Record.clone(db),
+		db:                db,
+		MaintenanceWindow: q.MaintenanceWindow.clone(db),
+		PortReservation:   q.PortReservation.clone(db),
+		Record:            q.Record.clone(db),
+		RecordAudit:       q.RecordAudit.clone(db),
 	}
 }
 
@@ -57,18 +72,27 @@ func (q *Query) WriteDB() *Query {
 
 func (q *Query) ReplaceDB(db *gorm.DB) *Query {
 	return &Query{
-		db:     db,
-		Record: q.Record.replaceDB(db),
+		db:                db,
+		MaintenanceWindow: q.MaintenanceWindow.replaceDB(db),
+		PortReservation:   q.PortReservation.replaceDB(db),
+		Record:            q.Record.replaceDB(db),
+		RecordAudit:       q.RecordAudit.replaceDB(db),
 	}
 }
 
 type queryCtx struct {
-	Record IRecordDo
+	MaintenanceWindow IMaintenanceWindowDo
+	PortReservation   IPortReservationDo
+	Record            IRecordDo
+	RecordAudit       IRecordAuditDo
 }
 
 func (q *Query) WithContext(ctx context.Context) *queryCtx {
 	return &queryCtx{
-		Record: q.Record.WithContext(ctx),
+		MaintenanceWindow: q.MaintenanceWindow.WithContext(ctx),
+		PortReservation:   q.PortReservation.WithContext(ctx),
+		Record:            q.Record.WithContext(ctx),
+		RecordAudit:       q.RecordAudit.WithContext(ctx),
 	}
 }
 
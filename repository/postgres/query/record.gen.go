@@ -35,6 +35,16 @@ func newRecord(db *gorm.DB, opts ...gen.DOOption) record {
 	_record.CreatedAt = field.NewTime(tableName, "created_at")
 	_record.UpdatedAt = field.NewTime(tableName, "updated_at")
 	_record.DeletedAt = field.NewField(tableName, "deleted_at")
+	_record.ExpiresAt = field.NewTime(tableName, "expires_at")
+	_record.ValidFrom = field.NewTime(tableName, "valid_from")
+	_record.ValidUntil = field.NewTime(tableName, "valid_until")
+	_record.Version = field.NewInt64(tableName, "version")
+	_record.Labels = field.NewString(tableName, "labels")
+	_record.Description = field.NewString(tableName, "description")
+	_record.Owner = field.NewString(tableName, "owner")
+	_record.Disabled = field.NewBool(tableName, "disabled")
+	_record.AliasOf = field.NewString(tableName, "alias_of")
+	_record.Tenant = field.NewString(tableName, "tenant")
 
 	_record.fillFieldMap()
 
@@ -44,15 +54,25 @@ func newRecord(db *gorm.DB, opts ...gen.DOOption) record {
 type record struct {
 	recordDo
 
-	ALL        field.Asterisk
-	ID         field.Int64
-	InstanceID field.Int64
-	Domain     field.String
-	Target     field.String
-	Port       field.Int32
-	CreatedAt  field.Time
-	UpdatedAt  field.Time
-	DeletedAt  field.Field
+	ALL         field.Asterisk
+	ID          field.Int64
+	InstanceID  field.Int64
+	Domain      field.String
+	Target      field.String
+	Port        field.Int32
+	CreatedAt   field.Time
+	UpdatedAt   field.Time
+	DeletedAt   field.Field
+	ExpiresAt   field.Time
+	ValidFrom   field.Time
+	ValidUntil  field.Time
+	Version     field.Int64
+	Labels      field.String
+	Description field.String
+	Owner       field.String
+	Disabled    field.Bool
+	AliasOf     field.String
+	Tenant      field.String
 
 	fieldMap map[string]field.Expr
 }
@@ -77,6 +97,16 @@ func (r *record) updateTableName(table string) *record {
 	r.CreatedAt = field.NewTime(table, "created_at")
 	r.UpdatedAt = field.NewTime(table, "updated_at")
 	r.DeletedAt = field.NewField(table, "deleted_at")
+	r.ExpiresAt = field.NewTime(table, "expires_at")
+	r.ValidFrom = field.NewTime(table, "valid_from")
+	r.ValidUntil = field.NewTime(table, "valid_until")
+	r.Version = field.NewInt64(table, "version")
+	r.Labels = field.NewString(table, "labels")
+	r.Description = field.NewString(table, "description")
+	r.Owner = field.NewString(table, "owner")
+	r.Disabled = field.NewBool(table, "disabled")
+	r.AliasOf = field.NewString(table, "alias_of")
+	r.Tenant = field.NewString(table, "tenant")
 
 	r.fillFieldMap()
 
@@ -93,7 +123,7 @@ func (r *record) GetFieldByName(fieldName string) (field.OrderExpr, bool) {
 }
 
 func (r *record) fillFieldMap() {
-	r.fieldMap = make(map[string]field.Expr, 8)
+	r.fieldMap = make(map[string]field.Expr, 18)
 	r.fieldMap["id"] = r.ID
 	r.fieldMap["instance_id"] = r.InstanceID
 	r.fieldMap["domain"] = r.Domain
@@ -102,6 +132,16 @@ func (r *record) fillFieldMap() {
 	r.fieldMap["created_at"] = r.CreatedAt
 	r.fieldMap["updated_at"] = r.UpdatedAt
 	r.fieldMap["deleted_at"] = r.DeletedAt
+	r.fieldMap["expires_at"] = r.ExpiresAt
+	r.fieldMap["valid_from"] = r.ValidFrom
+	r.fieldMap["valid_until"] = r.ValidUntil
+	r.fieldMap["version"] = r.Version
+	r.fieldMap["labels"] = r.Labels
+	r.fieldMap["description"] = r.Description
+	r.fieldMap["owner"] = r.Owner
+	r.fieldMap["disabled"] = r.Disabled
+	r.fieldMap["alias_of"] = r.AliasOf
+	r.fieldMap["tenant"] = r.Tenant
 }
 
 func (r record) clone(db *gorm.DB) record {
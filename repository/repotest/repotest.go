@@ -0,0 +1,1353 @@
+// Package repotest provides a conformance test suite for types.RecordRepository implementations
+//
+// A backend is checked by calling Run from one of its tests:
+//
+//	func TestRepository(t *testing.T) {
+//		repotest.Run(t, func(t *testing.T) types.RecordRepository {
+//			repo, err := file.NewRepository(filepath.Join(t.TempDir(), "records.bin"))
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//			return repo
+//		})
+//	}
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+// testTimeout bounds every test of the suite
+const testTimeout = 30 * time.Second
+
+// Factory creates a new, empty repository for a single test
+// The suite closes the repository when the test ends
+type Factory func(t *testing.T) types.RecordRepository
+
+// Run runs the conformance suite against the repositories created by newRepo
+func Run(t *testing.T, newRepo Factory) {
+	tests := []struct {
+		name string
+		fn   func(ctx context.Context, t *testing.T, repo types.RecordRepository)
+	}{
+		{"CreateAndFind", testCreateAndFind},
+		{"CreateInvalid", testCreateInvalid},
+		{"CreateDuplicate", testCreateDuplicate},
+		{"Timestamps", testTimestamps},
+		{"List", testList},
+		{"ListPagination", testListPagination},
+		{"FindByTarget", testFindByTarget},
+		{"FindByInstanceID", testFindByInstanceID},
+		{"Update", testUpdate},
+		{"Upsert", testUpsert},
+		{"Version", testVersion},
+		{"Metadata", testMetadata},
+		{"Alias", testAlias},
+		{"Tenant", testTenant},
+		{"Retarget", testRetarget},
+		{"RetargetPortShift", testRetargetPortShift},
+		{"RetargetPortZero", testRetargetPortZero},
+		{"Apply", testApply},
+		{"ApplyRollback", testApplyRollback},
+		{"Delete", testDelete},
+		{"DeleteByID", testDeleteByID},
+		{"DeleteByInstanceID", testDeleteByInstanceID},
+		{"RecreateDeleted", testRecreateDeleted},
+		{"Restore", testRestore},
+		{"Purge", testPurge},
+		{"Schedule", testSchedule},
+		{"Lease", testLease},
+		{"Audit", testAudit},
+		{"Ports", testPorts},
+		{"Maintenance", testMaintenance},
+		{"ConcurrentCreate", testConcurrentCreate},
+		{"Context", testContext},
+		{"Close", testClose},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), testTimeout)
+			defer cancel()
+
+			repo := newRepo(t)
+			t.Cleanup(func() {
+				repo.Close()
+			})
+			tt.fn(ctx, t, repo)
+		})
+	}
+}
+
+func newRecord(domain string) *types.Record {
+	return &types.Record{
+		InstanceID: 1,
+		Domain:     domain,
+		Target:     "192.0.2.1",
+		Port:       9987,
+	}
+}
+
+func mustCreate(ctx context.Context, t *testing.T, repo types.RecordRepository, record *types.Record) *types.Record {
+	t.Helper()
+	if err := repo.Create(ctx, record); err != nil {
+		t.Fatalf("Create(%s) error: %v", record.Domain, err)
+	}
+	return record
+}
+
+func expectError(t *testing.T, op string, err, want error) {
+	t.Helper()
+	if !errors.Is(err, want) {
+		t.Fatalf("%s error = %v, want %v", op, err, want)
+	}
+}
+
+func domains(records []*types.Record) map[string]int {
+	found := make(map[string]int)
+	for _, r := range records {
+		found[r.Domain]++
+	}
+	return found
+}
+
+func testCreateAndFind(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	record := mustCreate(ctx, t, repo, newRecord("a.example.com"))
+	if record.ID == 0 {
+		t.Fatalf("Create did not assign an ID")
+	}
+
+	found, err := repo.FindByDomain(ctx, "a.example.com")
+	if err != nil {
+		t.Fatalf("FindByDomain error: %v", err)
+	}
+	if found.ID != record.ID || found.Target != record.Target || found.Port != record.Port || found.InstanceID != record.InstanceID {
+		t.Fatalf("FindByDomain = %+v, want %+v", found, record)
+	}
+
+	// returned records must not alias stored ones
+	found.Target = "changed"
+	again, err := repo.FindByDomain(ctx, "a.example.com")
+	if err != nil {
+		t.Fatalf("FindByDomain error: %v", err)
+	}
+	if again.Target != record.Target {
+		t.Fatalf("modifying a returned record changed the stored one")
+	}
+
+	mustCreate(ctx, t, repo, newRecord("b.example.com"))
+	all, err := repo.Find(ctx)
+	if err != nil {
+		t.Fatalf("Find error: %v", err)
+	}
+	if got := domains(all); len(all) != 2 || got["a.example.com"] != 1 || got["b.example.com"] != 1 {
+		t.Fatalf("Find returned %v", got)
+	}
+
+	_, err = repo.FindByDomain(ctx, "missing.example.com")
+	expectError(t, "FindByDomain(missing)", err, types.ErrNotFound)
+}
+
+func testCreateInvalid(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	invalid := []*types.Record{
+		nil,
+		{Target: "192.0.2.1"},
+		{Domain: "a.example.com"},
+	}
+	for _, record := range invalid {
+		expectError(t, fmt.Sprintf("Create(%+v)", record), repo.Create(ctx, record), types.ErrInvalidRecord)
+	}
+
+	from := time.Now()
+	until := from.Add(-time.Hour)
+	record := newRecord("a.example.com")
+	record.ValidFrom, record.ValidUntil = &from, &until
+	expectError(t, "Create(inverted window)", repo.Create(ctx, record), types.ErrInvalidRecord)
+}
+
+func testCreateDuplicate(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	mustCreate(ctx, t, repo, newRecord("a.example.com"))
+
+	err := repo.Create(ctx, newRecord("a.example.com"))
+	expectError(t, "Create(duplicate)", err, types.ErrDomainExists)
+	expectError(t, "Create(duplicate)", err, types.ErrConflict)
+}
+
+func testTimestamps(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	before := time.Now().Add(-time.Second)
+	record := mustCreate(ctx, t, repo, newRecord("a.example.com"))
+	if record.CreatedAt.Before(before) || record.UpdatedAt.Before(before) {
+		t.Fatalf("Create timestamps not set: created %v updated %v", record.CreatedAt, record.UpdatedAt)
+	}
+	if record.DeletedAt != nil {
+		t.Fatalf("new record has DeletedAt set")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	update := *record
+	update.Target = "192.0.2.2"
+	if err := repo.Update(ctx, &update); err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+	if !update.UpdatedAt.After(record.UpdatedAt) {
+		t.Fatalf("Update did not advance UpdatedAt: %v -> %v", record.UpdatedAt, update.UpdatedAt)
+	}
+	// storage may keep less precision than time.Now
+	if !update.CreatedAt.Truncate(time.Millisecond).Equal(record.CreatedAt.Truncate(time.Millisecond)) {
+		t.Fatalf("Update changed CreatedAt: %v -> %v", record.CreatedAt, update.CreatedAt)
+	}
+}
+
+func testList(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	for i, domain := range []string{"a.example.com", "b.example.com", "a.example.org", "a_b.example.com"} {
+		record := newRecord(domain)
+		record.InstanceID = int64(i%2 + 1)
+		if i == 2 {
+			record.Target = "192.0.2.2"
+		}
+		mustCreate(ctx, t, repo, record)
+	}
+	if err := repo.Delete(ctx, "b.example.com"); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts types.ListOptions
+		want []string
+	}{
+		{"All", types.ListOptions{Sort: types.SortByDomain}, []string{"a.example.com", "a.example.org", "a_b.example.com"}},
+		{"Instance", types.ListOptions{InstanceID: 1}, []string{"a.example.com", "a.example.org"}},
+		{"Prefix", types.ListOptions{DomainPrefix: "a_"}, []string{"a_b.example.com"}},
+		{"Contains", types.ListOptions{DomainContains: ".example.com", Sort: types.SortByDomain, Descending: true}, []string{"a_b.example.com", "a.example.com"}},
+		{"Target", types.ListOptions{Target: "192.0.2.2"}, []string{"a.example.org"}},
+		{"NoMatch", types.ListOptions{InstanceID: 3}, nil},
+	}
+	for _, tt := range tests {
+		page, err := repo.List(ctx, tt.opts)
+		if err != nil {
+			t.Fatalf("List(%s) error: %v", tt.name, err)
+		}
+		if got := listedDomains(page.Records); !slices.Equal(got, tt.want) {
+			t.Errorf("List(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+		if page.NextCursor != "" {
+			t.Errorf("List(%s) returned a cursor without limit", tt.name)
+		}
+	}
+
+	_, err := repo.List(ctx, types.ListOptions{Cursor: "not a cursor"})
+	expectError(t, "List with a malformed cursor", err, types.ErrInvalidQuery)
+}
+
+func testListPagination(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	// domains sort by byte order, unlike in most database collations upper case sorts
+	// first and hyphens are not skipped
+	byteOrder := []string{"D.example.com", "a-c.example.com", "ab.example.com", "b.example.com", "e.example.com"}
+	for _, domain := range []string{"e.example.com", "b.example.com", "D.example.com", "ab.example.com", "a-c.example.com"} {
+		mustCreate(ctx, t, repo, newRecord(domain))
+	}
+
+	for _, sortField := range []types.SortField{types.SortByID, types.SortByDomain, types.SortByCreatedAt} {
+		for _, descending := range []bool{false, true} {
+			all, err := repo.List(ctx, types.ListOptions{Sort: sortField, Descending: descending})
+			if err != nil {
+				t.Fatalf("List error: %v", err)
+			}
+			want := listedDomains(all.Records)
+			if sortField == types.SortByDomain {
+				sorted := slices.Clone(byteOrder)
+				if descending {
+					slices.Reverse(sorted)
+				}
+				if !slices.Equal(want, sorted) {
+					t.Fatalf("List sorted by domain descending %v = %v, want %v", descending, want, sorted)
+				}
+			}
+
+			var got []string
+			opts := types.ListOptions{Sort: sortField, Descending: descending, Limit: 2}
+			for pages := 0; ; pages++ {
+				if pages > len(want) {
+					t.Fatalf("List sort %d did not reach the last page", sortField)
+				}
+				page, err := repo.List(ctx, opts)
+				if err != nil {
+					t.Fatalf("List page error: %v", err)
+				}
+				if len(page.Records) > opts.Limit {
+					t.Fatalf("List returned %d records, limit is %d", len(page.Records), opts.Limit)
+				}
+				got = append(got, listedDomains(page.Records)...)
+				if page.NextCursor == "" {
+					break
+				}
+				opts.Cursor = page.NextCursor
+			}
+			if !slices.Equal(got, want) {
+				t.Errorf("paginated List sort %d descending %v = %v, want %v", sortField, descending, got, want)
+			}
+		}
+	}
+}
+
+func listedDomains(records []*types.Record) []string {
+	var listed []string
+	for _, r := range records {
+		listed = append(listed, r.Domain)
+	}
+	return listed
+}
+
+func testFindByTarget(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	for _, domain := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		record := newRecord(domain)
+		if domain == "c.example.com" {
+			record.Target = "192.0.2.2"
+		}
+		mustCreate(ctx, t, repo, record)
+	}
+	if err := repo.Delete(ctx, "b.example.com"); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+
+	live, err := repo.FindByTarget(ctx, "192.0.2.1", false)
+	if err != nil {
+		t.Fatalf("FindByTarget error: %v", err)
+	}
+	if got := listedDomains(live); !slices.Equal(got, []string{"a.example.com"}) {
+		t.Errorf("FindByTarget = %v, want [a.example.com]", got)
+	}
+
+	all, err := repo.FindByTarget(ctx, "192.0.2.1", true)
+	if err != nil {
+		t.Fatalf("FindByTarget including deleted error: %v", err)
+	}
+	if got := listedDomains(all); !slices.Equal(got, []string{"a.example.com", "b.example.com"}) {
+		t.Errorf("FindByTarget including deleted = %v, want [a.example.com b.example.com]", got)
+	}
+	if all[1].DeletedAt == nil {
+		t.Errorf("FindByTarget did not report the deletion of b.example.com")
+	}
+
+	if none, err := repo.FindByTarget(ctx, "192.0.2.3", true); err != nil || len(none) != 0 {
+		t.Errorf("FindByTarget of an unused host = %d records, %v, want none", len(none), err)
+	}
+}
+
+func testFindByInstanceID(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	for i, domain := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		record := newRecord(domain)
+		record.InstanceID = int64(i%2 + 1)
+		mustCreate(ctx, t, repo, record)
+	}
+	if err := repo.Delete(ctx, "c.example.com"); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+
+	live, err := repo.FindByInstanceID(ctx, 1, false)
+	if err != nil {
+		t.Fatalf("FindByInstanceID error: %v", err)
+	}
+	if got := listedDomains(live); !slices.Equal(got, []string{"a.example.com"}) {
+		t.Errorf("FindByInstanceID = %v, want [a.example.com]", got)
+	}
+
+	all, err := repo.FindByInstanceID(ctx, 1, true)
+	if err != nil {
+		t.Fatalf("FindByInstanceID including deleted error: %v", err)
+	}
+	if got := listedDomains(all); !slices.Equal(got, []string{"a.example.com", "c.example.com"}) {
+		t.Errorf("FindByInstanceID including deleted = %v, want [a.example.com c.example.com]", got)
+	}
+}
+
+func testUpdate(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	record := mustCreate(ctx, t, repo, newRecord("a.example.com"))
+
+	// by domain
+	if err := repo.Update(ctx, &types.Record{Domain: "a.example.com", Target: "192.0.2.2", Port: 1, InstanceID: 2}); err != nil {
+		t.Fatalf("Update by domain error: %v", err)
+	}
+	found, err := repo.FindByDomain(ctx, "a.example.com")
+	if err != nil {
+		t.Fatalf("FindByDomain error: %v", err)
+	}
+	if found.ID != record.ID || found.Target != "192.0.2.2" || found.Port != 1 || found.InstanceID != 2 {
+		t.Fatalf("Update by domain stored %+v", found)
+	}
+
+	// by ID, the domain is filled in from the stored record
+	update := &types.Record{ID: record.ID, Target: "192.0.2.3", Port: 2}
+	if err = repo.Update(ctx, update); err != nil {
+		t.Fatalf("Update by ID error: %v", err)
+	}
+	if update.Domain != "a.example.com" {
+		t.Fatalf("Update by ID returned domain %q", update.Domain)
+	}
+
+	expectError(t, "Update(missing)", repo.Update(ctx, newRecord("missing.example.com")), types.ErrNotFound)
+	expectError(t, "Update(empty target)", repo.Update(ctx, &types.Record{ID: record.ID}), types.ErrInvalidRecord)
+}
+
+func testMetadata(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	record := newRecord("a.example.com")
+	record.Labels = map[string]string{"env": "prod", "team": "ops"}
+	record.Description = "production lobby"
+	record.Owner = "user-1"
+	mustCreate(ctx, t, repo, record)
+
+	other := newRecord("b.example.com")
+	other.Labels = map[string]string{"env": "staging"}
+	other.Owner = "user-2"
+	other.Disabled = true
+	mustCreate(ctx, t, repo, other)
+
+	// labels passed in are copied
+	record.Labels["env"] = "changed"
+	found, err := repo.FindByDomain(ctx, "a.example.com")
+	if err != nil {
+		t.Fatalf("FindByDomain error: %v", err)
+	}
+	if found.Labels["env"] != "prod" || found.Labels["team"] != "ops" || found.Description != "production lobby" ||
+		found.Owner != "user-1" || !found.Enabled() {
+		t.Fatalf("FindByDomain returned metadata %+v", found)
+	}
+
+	disabled, enabled := true, false
+	tests := []struct {
+		name string
+		opts types.ListOptions
+		want []string
+	}{
+		{"Owner", types.ListOptions{Owner: "user-2"}, []string{"b.example.com"}},
+		{"Label", types.ListOptions{Labels: map[string]string{"env": "prod"}}, []string{"a.example.com"}},
+		{"Labels", types.ListOptions{Labels: map[string]string{"env": "prod", "team": "dev"}}, nil},
+		{"Disabled", types.ListOptions{Disabled: &disabled}, []string{"b.example.com"}},
+		{"Enabled", types.ListOptions{Disabled: &enabled}, []string{"a.example.com"}},
+	}
+	for _, tt := range tests {
+		page, err := repo.List(ctx, tt.opts)
+		if err != nil {
+			t.Fatalf("List(%s) error: %v", tt.name, err)
+		}
+		if got := listedDomains(page.Records); !slices.Equal(got, tt.want) {
+			t.Errorf("List(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// a disabled record is still found so it can answer with a maintenance target
+	found, err = repo.FindByDomain(ctx, "b.example.com")
+	if err != nil {
+		t.Fatalf("FindByDomain(disabled) error: %v", err)
+	}
+	if found.Enabled() {
+		t.Fatalf("FindByDomain(disabled) returned an enabled record")
+	}
+
+	found.Disabled = false
+	found.Labels = nil
+	found.Description = "staging lobby"
+	if err = repo.Update(ctx, found); err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+	found, err = repo.FindByDomain(ctx, "b.example.com")
+	if err != nil {
+		t.Fatalf("FindByDomain error: %v", err)
+	}
+	if !found.Enabled() || len(found.Labels) != 0 || found.Description != "staging lobby" || found.Owner != "user-2" {
+		t.Fatalf("Update stored metadata %+v", found)
+	}
+}
+
+func testAlias(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	mustCreate(ctx, t, repo, newRecord("clan.example.com"))
+	alias := mustCreate(ctx, t, repo, &types.Record{Domain: "ts.clan.example.com", AliasOf: "clan.example.com"})
+	mustCreate(ctx, t, repo, &types.Record{Domain: "voice.clan.example.com", AliasOf: "clan.example.com"})
+
+	found, err := repo.FindByDomain(ctx, "ts.clan.example.com")
+	if err != nil {
+		t.Fatalf("FindByDomain(alias) error: %v", err)
+	}
+	if !found.Alias() || found.AliasOf != "clan.example.com" || found.Target != "" || found.Port != 0 {
+		t.Fatalf("FindByDomain(alias) returned %+v", found)
+	}
+
+	page, err := repo.List(ctx, types.ListOptions{AliasOf: "clan.example.com", Sort: types.SortByDomain})
+	if err != nil {
+		t.Fatalf("List(AliasOf) error: %v", err)
+	}
+	if got, want := listedDomains(page.Records), []string{"ts.clan.example.com", "voice.clan.example.com"}; !slices.Equal(got, want) {
+		t.Errorf("List(AliasOf) = %v, want %v", got, want)
+	}
+
+	// an alias can become a regular record and back
+	if err = repo.Update(ctx, &types.Record{ID: alias.ID, Target: "192.0.2.2"}); err != nil {
+		t.Fatalf("Update(alias to record) error: %v", err)
+	}
+	if err = repo.Update(ctx, &types.Record{ID: alias.ID, AliasOf: "voice.clan.example.com"}); err != nil {
+		t.Fatalf("Update(record to alias) error: %v", err)
+	}
+	found, err = repo.FindByDomain(ctx, "ts.clan.example.com")
+	if err != nil {
+		t.Fatalf("FindByDomain(alias) error: %v", err)
+	}
+	if found.AliasOf != "voice.clan.example.com" || found.Target != "" {
+		t.Fatalf("Update stored %+v", found)
+	}
+
+	expectError(t, "Create(alias with target)",
+		repo.Create(ctx, &types.Record{Domain: "a.example.com", Target: "192.0.2.1", AliasOf: "clan.example.com"}), types.ErrInvalidRecord)
+	expectError(t, "Create(alias of itself)",
+		repo.Create(ctx, &types.Record{Domain: "a.example.com", AliasOf: "a.example.com"}), types.ErrInvalidRecord)
+}
+
+func testTenant(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	brand := types.WithTenant(ctx, "brand")
+	mustCreate(ctx, t, repo, newRecord("a.example.com"))
+	// domains are unique per tenant
+	scoped := mustCreate(brand, t, repo, &types.Record{InstanceID: 1, Domain: "a.example.com", Target: "192.0.2.2"})
+	if scoped.Tenant != "brand" {
+		t.Fatalf("Create stored tenant %q, want brand", scoped.Tenant)
+	}
+	mustCreate(brand, t, repo, newRecord("b.example.com"))
+
+	found, err := repo.FindByDomain(ctx, "a.example.com")
+	if err != nil {
+		t.Fatalf("FindByDomain(default tenant) error: %v", err)
+	}
+	if found.Target != "192.0.2.1" || found.Tenant != "" {
+		t.Fatalf("FindByDomain(default tenant) returned %+v", found)
+	}
+	found, err = repo.FindByDomain(brand, "a.example.com")
+	if err != nil {
+		t.Fatalf("FindByDomain(brand) error: %v", err)
+	}
+	if found.Target != "192.0.2.2" || found.Tenant != "brand" {
+		t.Fatalf("FindByDomain(brand) returned %+v", found)
+	}
+	_, err = repo.FindByDomain(ctx, "b.example.com")
+	expectError(t, "FindByDomain(other tenant)", err, types.ErrNotFound)
+
+	all, err := repo.Find(brand)
+	if err != nil {
+		t.Fatalf("Find(brand) error: %v", err)
+	}
+	if got := domains(all); len(all) != 2 || got["a.example.com"] != 1 || got["b.example.com"] != 1 {
+		t.Fatalf("Find(brand) returned %v", got)
+	}
+	byInstance, err := repo.FindByInstanceID(ctx, 1, false)
+	if err != nil {
+		t.Fatalf("FindByInstanceID(default tenant) error: %v", err)
+	}
+	if len(byInstance) != 1 {
+		t.Fatalf("FindByInstanceID(default tenant) returned %d records, want 1", len(byInstance))
+	}
+
+	// a record can neither be changed nor moved across tenants
+	expectError(t, "Update(other tenant)", repo.Update(ctx, &types.Record{ID: scoped.ID, Target: "192.0.2.3"}), types.ErrNotFound)
+	expectError(t, "DeleteByID(other tenant)", repo.DeleteByID(ctx, scoped.ID), types.ErrNotFound)
+	expectError(t, "Create(foreign record)", repo.Create(ctx, &types.Record{Domain: "c.example.com", Target: "192.0.2.1", Tenant: "brand"}), types.ErrInvalidRecord)
+
+	if err = repo.DeleteByInstanceID(brand, 1); err != nil {
+		t.Fatalf("DeleteByInstanceID(brand) error: %v", err)
+	}
+	if _, err = repo.FindByDomain(ctx, "a.example.com"); err != nil {
+		t.Fatalf("DeleteByInstanceID(brand) removed a record of the default tenant: %v", err)
+	}
+	deleted, err := repo.ListDeleted(ctx)
+	if err != nil {
+		t.Fatalf("ListDeleted(default tenant) error: %v", err)
+	}
+	if len(deleted) != 0 {
+		t.Fatalf("ListDeleted(default tenant) returned %d records of another tenant", len(deleted))
+	}
+}
+
+func testVersion(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	record := mustCreate(ctx, t, repo, newRecord("a.example.com"))
+	if record.Version != 1 {
+		t.Fatalf("created record has version %d, want 1", record.Version)
+	}
+
+	first, second := *record, *record
+	first.Target = "192.0.2.2"
+	if err := repo.Update(ctx, &first); err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+	if first.Version != 2 {
+		t.Fatalf("updated record has version %d, want 2", first.Version)
+	}
+
+	second.Target = "192.0.2.3"
+	err := repo.Update(ctx, &second)
+	var conflict *types.VersionConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, types.ErrConflict) {
+		t.Fatalf("Update with an outdated version error = %v, want a VersionConflictError", err)
+	}
+	if conflict.Expected != 1 || conflict.Actual != 2 || conflict.ID != record.ID {
+		t.Errorf("VersionConflictError = %+v, want version 1 expected and 2 stored", conflict)
+	}
+	if found, _ := repo.FindByDomain(ctx, "a.example.com"); found == nil || found.Target != "192.0.2.2" || found.Version != 2 {
+		t.Fatalf("conflicting Update changed the record")
+	}
+
+	// version 0 updates unconditionally
+	unconditional := newRecord("a.example.com")
+	unconditional.Target = "192.0.2.4"
+	if err = repo.Upsert(ctx, unconditional); err != nil {
+		t.Fatalf("Upsert error: %v", err)
+	}
+	if unconditional.Version != 3 {
+		t.Errorf("upserted record has version %d, want 3", unconditional.Version)
+	}
+
+	if err = repo.Renew(ctx, "a.example.com", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Renew error: %v", err)
+	}
+	if found, _ := repo.FindByDomain(ctx, "a.example.com"); found == nil || found.Version != 3 {
+		t.Errorf("Renew changed the record version")
+	}
+}
+
+func testUpsert(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	record := newRecord("a.example.com")
+	if err := repo.Upsert(ctx, record); err != nil {
+		t.Fatalf("Upsert(create) error: %v", err)
+	}
+	if record.ID == 0 {
+		t.Fatalf("Upsert(create) did not assign an ID")
+	}
+
+	update := newRecord("a.example.com")
+	update.Target = "192.0.2.2"
+	if err := repo.Upsert(ctx, update); err != nil {
+		t.Fatalf("Upsert(update) error: %v", err)
+	}
+	if update.ID != record.ID {
+		t.Fatalf("Upsert(update) created a new record %d, want %d", update.ID, record.ID)
+	}
+
+	all, err := repo.Find(ctx)
+	if err != nil {
+		t.Fatalf("Find error: %v", err)
+	}
+	if len(all) != 1 || all[0].Target != "192.0.2.2" {
+		t.Fatalf("Find after Upsert returned %d records", len(all))
+	}
+}
+
+func testRetarget(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	for i, domain := range []string{"a.example.com", "b.example.com", "c.example.com", "d.example.com"} {
+		record := newRecord(domain)
+		record.Port = int32(9987 + i%2)
+		if domain == "d.example.com" {
+			record.Target = "192.0.2.2"
+		}
+		mustCreate(ctx, t, repo, record)
+	}
+
+	from := types.Endpoint{Target: "192.0.2.1", Port: 9987}
+	to := types.Endpoint{Target: "192.0.2.9", Port: 10000}
+	diffs, err := repo.Retarget(ctx, from, to, types.RetargetOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Retarget dry run error: %v", err)
+	}
+	if len(diffs) != 2 || diffs[0].Before.Domain != "a.example.com" || diffs[1].Before.Domain != "c.example.com" {
+		t.Fatalf("Retarget dry run returned %d diffs, want a.example.com and c.example.com", len(diffs))
+	}
+	if diffs[0].After.Target != "192.0.2.9" || diffs[0].After.Port != 10000 || diffs[0].Before.Target != "192.0.2.1" {
+		t.Fatalf("Retarget diff = %+v -> %+v", diffs[0].Before, diffs[0].After)
+	}
+	if found, _ := repo.FindByDomain(ctx, "a.example.com"); found == nil || found.Target != "192.0.2.1" {
+		t.Fatalf("Retarget dry run changed a.example.com")
+	}
+
+	if diffs, err = repo.Retarget(ctx, from, to, types.RetargetOptions{}); err != nil || len(diffs) != 2 {
+		t.Fatalf("Retarget = %d diffs, %v, want 2", len(diffs), err)
+	}
+	for domain, want := range map[string]types.Endpoint{
+		"a.example.com": to,
+		"b.example.com": {Target: "192.0.2.1", Port: 9988},
+		"c.example.com": to,
+		"d.example.com": {Target: "192.0.2.2", Port: 9988},
+	} {
+		found, err := repo.FindByDomain(ctx, domain)
+		if err != nil {
+			t.Fatalf("FindByDomain(%s) error: %v", domain, err)
+		}
+		if got := (types.Endpoint{Target: found.Target, Port: found.Port}); got != want {
+			t.Errorf("%s points to %s after Retarget, want %s", domain, got, want)
+		}
+	}
+
+	_, err = repo.Retarget(ctx, types.Endpoint{}, to, types.RetargetOptions{})
+	expectError(t, "Retarget without a source", err, types.ErrInvalidQuery)
+}
+
+func testRetargetPortShift(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	for i, domain := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		record := newRecord(domain)
+		record.Port = int32(10000 + i*10)
+		mustCreate(ctx, t, repo, record)
+	}
+
+	from := types.Endpoint{Target: "192.0.2.1"}
+	diffs, err := repo.Retarget(ctx, from, types.Endpoint{}, types.RetargetOptions{PortMin: 10000, PortMax: 10010, PortShift: 100})
+	if err != nil || len(diffs) != 2 {
+		t.Fatalf("Retarget port shift = %d diffs, %v, want 2", len(diffs), err)
+	}
+	for domain, want := range map[string]int32{"a.example.com": 10100, "b.example.com": 10110, "c.example.com": 10020} {
+		found, err := repo.FindByDomain(ctx, domain)
+		if err != nil {
+			t.Fatalf("FindByDomain(%s) error: %v", domain, err)
+		}
+		if found.Port != want || found.Target != "192.0.2.1" {
+			t.Errorf("%s points to %s:%d after port shift, want port %d", domain, found.Target, found.Port, want)
+		}
+	}
+
+	// a shift moving any record out of range changes nothing
+	_, err = repo.Retarget(ctx, from, types.Endpoint{}, types.RetargetOptions{PortShift: 60000})
+	expectError(t, "Retarget out of the port range", err, types.ErrInvalidRecord)
+	if found, _ := repo.FindByDomain(ctx, "c.example.com"); found == nil || found.Port != 10020 {
+		t.Errorf("failed Retarget changed c.example.com")
+	}
+}
+
+func testRetargetPortZero(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	record := newRecord("a.example.com")
+	record.Port = 0
+	mustCreate(ctx, t, repo, record)
+	mustCreate(ctx, t, repo, newRecord("b.example.com"))
+
+	from := types.Endpoint{Target: "192.0.2.1"}
+	diffs, err := repo.Retarget(ctx, from, types.Endpoint{Target: "192.0.2.9"}, types.RetargetOptions{})
+	if err != nil || len(diffs) != 2 {
+		t.Fatalf("Retarget with a port 0 record = %d diffs, %v, want 2", len(diffs), err)
+	}
+	if found, _ := repo.FindByDomain(ctx, "a.example.com"); found == nil || found.Target != "192.0.2.9" || found.Port != 0 {
+		t.Errorf("a.example.com = %+v after Retarget, want 192.0.2.9 on port 0", found)
+	}
+
+	// a port shift leaves the client default port alone
+	diffs, err = repo.Retarget(ctx, types.Endpoint{Target: "192.0.2.9"}, types.Endpoint{}, types.RetargetOptions{PortShift: 10})
+	if err != nil || len(diffs) != 2 {
+		t.Fatalf("Retarget port shift with a port 0 record = %d diffs, %v, want 2", len(diffs), err)
+	}
+	if found, _ := repo.FindByDomain(ctx, "a.example.com"); found == nil || found.Port != 0 {
+		t.Errorf("a.example.com = %+v after port shift, want port 0", found)
+	}
+
+	// a destination port is still checked
+	_, err = repo.Retarget(ctx, types.Endpoint{Target: "192.0.2.9"}, types.Endpoint{Port: -1}, types.RetargetOptions{})
+	expectError(t, "Retarget to an invalid port", err, types.ErrInvalidRecord)
+}
+
+func testApply(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	mustCreate(ctx, t, repo, newRecord("a.example.com"))
+	mustCreate(ctx, t, repo, newRecord("b.example.com"))
+
+	created := newRecord("c.example.com")
+	updated := newRecord("a.example.com")
+	updated.Target = "192.0.2.2"
+	err := repo.Apply(ctx, []types.Change{
+		{Op: types.ChangeCreate, Record: created},
+		{Op: types.ChangeUpdate, Record: updated},
+		{Op: types.ChangeDelete, Domain: "b.example.com"},
+	})
+	if err != nil {
+		t.Fatalf("Apply error: %v", err)
+	}
+	if created.ID == 0 || updated.ID == 0 {
+		t.Errorf("Apply did not fill in the records, IDs %d and %d", created.ID, updated.ID)
+	}
+
+	records, err := repo.Find(ctx)
+	if err != nil {
+		t.Fatalf("Find error: %v", err)
+	}
+	if got := domains(records); len(got) != 2 || got["a.example.com"] != 1 || got["c.example.com"] != 1 {
+		t.Fatalf("Find after Apply = %v, want a.example.com and c.example.com", got)
+	}
+	if found, _ := repo.FindByDomain(ctx, "a.example.com"); found == nil || found.Target != "192.0.2.2" {
+		t.Errorf("Apply did not update a.example.com")
+	}
+}
+
+func testApplyRollback(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	mustCreate(ctx, t, repo, newRecord("a.example.com"))
+
+	err := repo.Apply(ctx, []types.Change{
+		{Op: types.ChangeCreate, Record: newRecord("b.example.com")},
+		{Op: types.ChangeDelete, Domain: "a.example.com"},
+		{Op: types.ChangeCreate, Record: newRecord("c.example.com")},
+		{Op: types.ChangeCreate, Record: newRecord("c.example.com")},
+	})
+	expectError(t, "Apply with a duplicate", err, types.ErrDomainExists)
+	if err == nil || !strings.HasPrefix(err.Error(), "change 3:") {
+		t.Errorf("Apply error %q does not name the failing change", err)
+	}
+
+	records, err := repo.Find(ctx)
+	if err != nil {
+		t.Fatalf("Find error: %v", err)
+	}
+	if got := domains(records); len(got) != 1 || got["a.example.com"] != 1 {
+		t.Fatalf("Find after failed Apply = %v, want only a.example.com", got)
+	}
+}
+
+func testDelete(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	mustCreate(ctx, t, repo, newRecord("a.example.com"))
+	mustCreate(ctx, t, repo, newRecord("b.example.com"))
+
+	if err := repo.Delete(ctx, "a.example.com"); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+
+	_, err := repo.FindByDomain(ctx, "a.example.com")
+	expectError(t, "FindByDomain(deleted)", err, types.ErrNotFound)
+
+	all, err := repo.Find(ctx)
+	if err != nil {
+		t.Fatalf("Find error: %v", err)
+	}
+	if got := domains(all); len(all) != 1 || got["b.example.com"] != 1 {
+		t.Fatalf("Find after Delete returned %v", got)
+	}
+
+	expectError(t, "Delete(deleted)", repo.Delete(ctx, "a.example.com"), types.ErrNotFound)
+	expectError(t, "Delete(missing)", repo.Delete(ctx, "missing.example.com"), types.ErrNotFound)
+	expectError(t, "Update(deleted)", repo.Update(ctx, newRecord("a.example.com")), types.ErrNotFound)
+}
+
+func testDeleteByID(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	record := mustCreate(ctx, t, repo, newRecord("a.example.com"))
+
+	if err := repo.DeleteByID(ctx, record.ID); err != nil {
+		t.Fatalf("DeleteByID error: %v", err)
+	}
+	_, err := repo.FindByDomain(ctx, "a.example.com")
+	expectError(t, "FindByDomain(deleted)", err, types.ErrNotFound)
+
+	expectError(t, "DeleteByID(deleted)", repo.DeleteByID(ctx, record.ID), types.ErrNotFound)
+	expectError(t, "DeleteByID(missing)", repo.DeleteByID(ctx, record.ID+1000), types.ErrNotFound)
+}
+
+func testDeleteByInstanceID(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	for i, domain := range []string{"a.example.com", "b.example.com", "c.example.com"} {
+		record := newRecord(domain)
+		record.InstanceID = int64(i%2 + 1)
+		mustCreate(ctx, t, repo, record)
+	}
+
+	if err := repo.DeleteByInstanceID(ctx, 1); err != nil {
+		t.Fatalf("DeleteByInstanceID error: %v", err)
+	}
+
+	all, err := repo.Find(ctx)
+	if err != nil {
+		t.Fatalf("Find error: %v", err)
+	}
+	if got := domains(all); len(all) != 1 || got["b.example.com"] != 1 {
+		t.Fatalf("Find after DeleteByInstanceID returned %v", got)
+	}
+
+	// deleting an instance without records is not an error
+	if err = repo.DeleteByInstanceID(ctx, 1); err != nil {
+		t.Fatalf("DeleteByInstanceID(empty) error: %v", err)
+	}
+}
+
+func testRecreateDeleted(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	first := mustCreate(ctx, t, repo, newRecord("a.example.com"))
+	if err := repo.Delete(ctx, "a.example.com"); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+
+	second := newRecord("a.example.com")
+	second.Target = "192.0.2.2"
+	mustCreate(ctx, t, repo, second)
+	if second.ID == first.ID {
+		t.Fatalf("re-created record reused ID %d", first.ID)
+	}
+
+	found, err := repo.FindByDomain(ctx, "a.example.com")
+	if err != nil {
+		t.Fatalf("FindByDomain error: %v", err)
+	}
+	if found.Target != "192.0.2.2" {
+		t.Fatalf("FindByDomain returned the deleted record")
+	}
+}
+
+func testRestore(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	_, err := repo.Restore(ctx, "a.example.com")
+	expectError(t, "Restore of a live domain", err, types.ErrNotFound)
+
+	mustCreate(ctx, t, repo, newRecord("a.example.com"))
+	mustCreate(ctx, t, repo, newRecord("b.example.com"))
+	if err = repo.Delete(ctx, "a.example.com"); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+
+	deleted, err := repo.ListDeleted(ctx)
+	if err != nil {
+		t.Fatalf("ListDeleted error: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0].Domain != "a.example.com" || deleted[0].DeletedAt == nil {
+		t.Fatalf("ListDeleted returned %v, want the deleted a.example.com", domains(deleted))
+	}
+
+	restored, err := repo.Restore(ctx, "a.example.com")
+	if err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+	if len(restored) != 1 || restored[0].DeletedAt != nil {
+		t.Fatalf("Restore returned %d records, want 1 live record", len(restored))
+	}
+	if _, err = repo.FindByDomain(ctx, "a.example.com"); err != nil {
+		t.Fatalf("FindByDomain after Restore error: %v", err)
+	}
+	if deleted, err = repo.ListDeleted(ctx); err != nil || len(deleted) != 0 {
+		t.Fatalf("ListDeleted after Restore = %d records, %v, want none", len(deleted), err)
+	}
+
+	if err = repo.Delete(ctx, "a.example.com"); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	mustCreate(ctx, t, repo, newRecord("a.example.com"))
+	_, err = repo.Restore(ctx, "a.example.com")
+	expectError(t, "Restore of a re-created domain", err, types.ErrDomainExists)
+}
+
+func testPurge(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	mustCreate(ctx, t, repo, newRecord("a.example.com"))
+	mustCreate(ctx, t, repo, newRecord("b.example.com"))
+	if err := repo.Delete(ctx, "a.example.com"); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+
+	count, err := repo.Purge(ctx, time.Now().Add(-time.Hour))
+	if err != nil || count != 0 {
+		t.Fatalf("Purge of recent deletions = %d, %v, want 0", count, err)
+	}
+
+	count, err = repo.Purge(ctx, time.Now().Add(time.Second))
+	if err != nil || count != 1 {
+		t.Fatalf("Purge = %d, %v, want 1", count, err)
+	}
+	if deleted, err := repo.ListDeleted(ctx); err != nil || len(deleted) != 0 {
+		t.Fatalf("ListDeleted after Purge = %d records, %v, want none", len(deleted), err)
+	}
+	_, err = repo.Restore(ctx, "a.example.com")
+	expectError(t, "Restore of a purged domain", err, types.ErrNotFound)
+
+	if _, err = repo.FindByDomain(ctx, "b.example.com"); err != nil {
+		t.Fatalf("Purge removed a live record: %v", err)
+	}
+
+	if audited, ok := repo.(types.AuditRepository); ok {
+		if history, err := audited.History(ctx, "a.example.com"); err != nil || len(history) != 0 {
+			t.Errorf("History of a purged record = %d entries, %v, want none", len(history), err)
+		}
+		if history, err := audited.History(ctx, "b.example.com"); err != nil || len(history) != 1 {
+			t.Errorf("History of a live record after Purge = %d entries, %v, want 1", len(history), err)
+		}
+	}
+}
+
+func testSchedule(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	now := time.Now()
+	from, until := now.Add(-time.Minute), now.Add(time.Hour)
+	futureFrom, futureUntil := now.Add(2*time.Hour), now.Add(3*time.Hour)
+
+	mustCreate(ctx, t, repo, newRecord("a.example.com"))
+
+	active := newRecord("a.example.com")
+	active.Target = "192.0.2.2"
+	active.ValidFrom, active.ValidUntil = &from, &until
+	mustCreate(ctx, t, repo, active)
+
+	future := newRecord("a.example.com")
+	future.Target = "192.0.2.3"
+	future.ValidFrom, future.ValidUntil = &futureFrom, &futureUntil
+	mustCreate(ctx, t, repo, future)
+
+	found, err := repo.FindByDomain(ctx, "a.example.com")
+	if err != nil {
+		t.Fatalf("FindByDomain error: %v", err)
+	}
+	if found.ID != active.ID {
+		t.Fatalf("FindByDomain returned %s, want the active scheduled record", found.Target)
+	}
+
+	all, err := repo.Find(ctx)
+	if err != nil {
+		t.Fatalf("Find error: %v", err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("Find returned %d records, want 3", len(all))
+	}
+
+	if err = repo.DeleteByID(ctx, active.ID); err != nil {
+		t.Fatalf("DeleteByID error: %v", err)
+	}
+	found, err = repo.FindByDomain(ctx, "a.example.com")
+	if err != nil {
+		t.Fatalf("FindByDomain error: %v", err)
+	}
+	if found.Target != "192.0.2.1" {
+		t.Fatalf("FindByDomain returned %s after the schedule was removed", found.Target)
+	}
+
+	if err = repo.Delete(ctx, "a.example.com"); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if all, err = repo.Find(ctx); err != nil || len(all) != 0 {
+		t.Fatalf("Find after Delete returned %d records, %v", len(all), err)
+	}
+}
+
+func testLease(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	past := time.Now().Add(-time.Minute)
+	for _, domain := range []string{"a.example.com", "b.example.com"} {
+		record := newRecord(domain)
+		record.ExpiresAt = &past
+		mustCreate(ctx, t, repo, record)
+	}
+
+	future := time.Now().Add(time.Hour)
+	if err := repo.Renew(ctx, "a.example.com", future); err != nil {
+		t.Fatalf("Renew error: %v", err)
+	}
+	expectError(t, "Renew(missing)", repo.Renew(ctx, "missing.example.com", future), types.ErrNotFound)
+
+	count, err := repo.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("DeleteExpired error: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("DeleteExpired removed %d records, want 1", count)
+	}
+
+	all, err := repo.Find(ctx)
+	if err != nil {
+		t.Fatalf("Find error: %v", err)
+	}
+	if got := domains(all); len(all) != 1 || got["a.example.com"] != 1 {
+		t.Fatalf("Find after DeleteExpired returned %v", got)
+	}
+
+	if err = repo.RenewByInstanceID(ctx, 1, past); err != nil {
+		t.Fatalf("RenewByInstanceID error: %v", err)
+	}
+	if count, err = repo.DeleteExpired(ctx, time.Now()); err != nil || count != 1 {
+		t.Fatalf("DeleteExpired after RenewByInstanceID removed %d records, %v", count, err)
+	}
+}
+
+func testAudit(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	audited, ok := repo.(types.AuditRepository)
+	if !ok {
+		t.Skip("repository keeps no audit trail")
+	}
+
+	record := mustCreate(ctx, t, repo, newRecord("a.example.com"))
+	mustCreate(ctx, t, repo, newRecord("b.example.com"))
+
+	updated := *record
+	updated.Target = "192.0.2.2"
+	if err := repo.Update(types.WithActor(ctx, "alice"), &updated); err != nil {
+		t.Fatalf("Update error: %v", err)
+	}
+	if err := repo.Delete(types.WithActor(ctx, "bob"), "a.example.com"); err != nil {
+		t.Fatalf("Delete error: %v", err)
+	}
+	if _, err := repo.Restore(ctx, "a.example.com"); err != nil {
+		t.Fatalf("Restore error: %v", err)
+	}
+	// a failed batch leaves no entries behind
+	_ = repo.Apply(ctx, []types.Change{
+		{Op: types.ChangeDelete, Domain: "a.example.com"},
+		{Op: types.ChangeDelete, Domain: "c.example.com"},
+	})
+
+	history, err := audited.History(ctx, "a.example.com")
+	if err != nil {
+		t.Fatalf("History error: %v", err)
+	}
+	want := []struct {
+		action types.AuditAction
+		actor  string
+	}{
+		{types.AuditCreate, ""},
+		{types.AuditUpdate, "alice"},
+		{types.AuditDelete, "bob"},
+		{types.AuditRestore, ""},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("History returned %d entries, want %d", len(history), len(want))
+	}
+	for i, entry := range history {
+		if entry.Action != want[i].action || entry.Actor != want[i].actor || entry.RecordID != record.ID {
+			t.Errorf("entry %d = %s by %q for record %d, want %s by %q", i, entry.Action, entry.Actor, entry.RecordID, want[i].action, want[i].actor)
+		}
+		if i > 0 && entry.Time.Before(history[i-1].Time) {
+			t.Errorf("entry %d is older than entry %d", i, i-1)
+		}
+	}
+
+	if history[0].Old != nil || history[0].New == nil || history[0].New.Target != "192.0.2.1" {
+		t.Errorf("create entry does not hold the created record")
+	}
+	if history[1].Old == nil || history[1].Old.Target != "192.0.2.1" || history[1].New == nil || history[1].New.Target != "192.0.2.2" {
+		t.Errorf("update entry does not hold the old and new targets")
+	}
+	if history[1].New.Version != 2 {
+		t.Errorf("update entry holds version %d, want 2", history[1].New.Version)
+	}
+	if history[2].Old == nil || history[2].New != nil {
+		t.Errorf("delete entry does not hold only the deleted record")
+	}
+}
+
+func testPorts(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	reserver, ok := repo.(types.PortRepository)
+	if !ok {
+		t.Skip("repository cannot reserve ports")
+	}
+
+	ports := types.PortRange{Min: 9987, Max: 9990}
+	// 9987 is used by a record and not handed out
+	mustCreate(ctx, t, repo, newRecord("a.example.com"))
+	port, err := reserver.ReservePort(ctx, "192.0.2.1", ports, 1)
+	if err != nil {
+		t.Fatalf("ReservePort error: %v", err)
+	}
+	if port != 9988 {
+		t.Fatalf("ReservePort = %d, want 9988", port)
+	}
+	// ports are reserved per host
+	if port, err = reserver.ReservePort(ctx, "192.0.2.2", ports, 1); err != nil || port != 9987 {
+		t.Fatalf("ReservePort(other host) = %d, %v, want 9987", port, err)
+	}
+
+	const workers = 4
+	var wg sync.WaitGroup
+	results := make(chan error, workers)
+	reserved := make(chan int32, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(instanceID int64) {
+			defer wg.Done()
+			port, err := reserver.ReservePort(ctx, "192.0.2.1", ports, instanceID)
+			if err == nil {
+				reserved <- port
+			}
+			results <- err
+		}(int64(i + 2))
+	}
+	wg.Wait()
+	close(results)
+	close(reserved)
+
+	var exhausted int
+	for err := range results {
+		switch {
+		case err == nil:
+		case errors.Is(err, types.ErrNoFreePort):
+			exhausted++
+		default:
+			t.Fatalf("concurrent ReservePort error: %v", err)
+		}
+	}
+	seen := make(map[int32]bool)
+	for port := range reserved {
+		if seen[port] || port == 9987 || port == 9988 {
+			t.Fatalf("concurrent ReservePort returned port %d twice", port)
+		}
+		seen[port] = true
+	}
+	if len(seen) != 2 || exhausted != workers-2 {
+		t.Fatalf("concurrent ReservePort reserved %d ports, %d found none, want 2 and %d", len(seen), exhausted, workers-2)
+	}
+
+	released, err := reserver.ReleasePorts(ctx, 1)
+	if err != nil || released != 2 {
+		t.Fatalf("ReleasePorts = %d, %v, want 2", released, err)
+	}
+	if port, err = reserver.ReservePort(ctx, "192.0.2.1", ports, 1); err != nil || port != 9988 {
+		t.Fatalf("ReservePort after ReleasePorts = %d, %v, want 9988", port, err)
+	}
+
+	_, err = reserver.ReservePort(ctx, "192.0.2.1", types.PortRange{Min: 10, Max: 9}, 1)
+	expectError(t, "ReservePort(invalid range)", err, types.ErrInvalidQuery)
+}
+
+func testMaintenance(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	windows, ok := repo.(types.MaintenanceRepository)
+	if !ok {
+		t.Skip("repository cannot persist maintenance windows")
+	}
+
+	from := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	global := &types.Maintenance{Scope: types.GlobalScope(), Target: types.Endpoint{Target: "maintenance.example.com", Port: 9987}}
+	if err := windows.CreateMaintenance(ctx, global); err != nil {
+		t.Fatalf("CreateMaintenance error: %v", err)
+	}
+	if global.ID == 0 || global.CreatedAt.IsZero() {
+		t.Fatalf("CreateMaintenance did not set ID and creation time: %+v", global)
+	}
+	scheduled := &types.Maintenance{Scope: types.InstanceScope(1), From: &from}
+	if err := windows.CreateMaintenance(ctx, scheduled); err != nil {
+		t.Fatalf("CreateMaintenance(scheduled) error: %v", err)
+	}
+	if err := windows.CreateMaintenance(types.WithTenant(ctx, "brand"), &types.Maintenance{Scope: types.DomainScope("a.example.com")}); err != nil {
+		t.Fatalf("CreateMaintenance(brand) error: %v", err)
+	}
+	expectError(t, "CreateMaintenance(invalid)",
+		windows.CreateMaintenance(ctx, &types.Maintenance{Scope: types.InstanceScope(0)}), types.ErrInvalidMaintenance)
+
+	found, err := windows.FindMaintenance(ctx)
+	if err != nil {
+		t.Fatalf("FindMaintenance error: %v", err)
+	}
+	if len(found) != 2 || found[0].ID != global.ID || found[1].ID != scheduled.ID {
+		t.Fatalf("FindMaintenance returned %d windows, want the 2 of the default tenant", len(found))
+	}
+	if found[0].Target != global.Target || found[0].Scope != global.Scope {
+		t.Errorf("FindMaintenance returned %+v, want %+v", found[0], global)
+	}
+	if found[1].Scope.InstanceID != 1 || found[1].From == nil || !found[1].From.Equal(from) || found[1].Until != nil {
+		t.Errorf("FindMaintenance returned schedule %v-%v, want %v-", found[1].From, found[1].Until, from)
+	}
+
+	if err = windows.DeleteMaintenance(ctx, global.ID); err != nil {
+		t.Fatalf("DeleteMaintenance error: %v", err)
+	}
+	expectError(t, "DeleteMaintenance(deleted)", windows.DeleteMaintenance(ctx, global.ID), types.ErrNotFound)
+	if found, err = windows.FindMaintenance(ctx); err != nil || len(found) != 1 {
+		t.Fatalf("FindMaintenance after DeleteMaintenance returned %d windows, %v", len(found), err)
+	}
+
+	// concurrent replacements of a scope leave one running window, the scheduled one is kept
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			target := types.Endpoint{Target: fmt.Sprintf("%d.maintenance.example.com", i)}
+			_, err := windows.ReplaceMaintenance(ctx, &types.Maintenance{Scope: types.InstanceScope(1), Target: target}, time.Now())
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("ReplaceMaintenance error: %v", err)
+		}
+	}
+	if found, err = windows.FindMaintenance(ctx); err != nil || len(found) != 2 || found[0].ID != scheduled.ID {
+		t.Fatalf("FindMaintenance after ReplaceMaintenance returned %d windows, %v, want the scheduled and one running", len(found), err)
+	}
+
+	replacement := &types.Maintenance{Scope: types.InstanceScope(1)}
+	replaced, err := windows.ReplaceMaintenance(ctx, replacement, time.Now())
+	if err != nil || len(replaced) != 1 || replaced[0] != found[1].ID || replacement.ID == 0 {
+		t.Fatalf("ReplaceMaintenance = %v, %v, want window %d replaced", replaced, err, found[1].ID)
+	}
+	_, err = windows.ReplaceMaintenance(ctx, &types.Maintenance{Scope: types.DomainScope("")}, time.Now())
+	expectError(t, "ReplaceMaintenance(invalid)", err, types.ErrInvalidMaintenance)
+}
+
+func testConcurrentCreate(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	const workers = 16
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			errs <- repo.Create(ctx, newRecord(fmt.Sprintf("%d.example.com", i)))
+		}(i)
+		go func() {
+			defer wg.Done()
+			errs <- repo.Create(ctx, newRecord("shared.example.com"))
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var failed int
+	for err := range errs {
+		switch {
+		case err == nil:
+		case errors.Is(err, types.ErrDomainExists):
+			failed++
+		default:
+			t.Fatalf("concurrent Create error: %v", err)
+		}
+	}
+	if failed != workers-1 {
+		t.Fatalf("%d concurrent creates of the same domain failed, want %d", failed, workers-1)
+	}
+
+	all, err := repo.Find(ctx)
+	if err != nil {
+		t.Fatalf("Find error: %v", err)
+	}
+	if len(all) != workers+1 {
+		t.Fatalf("Find returned %d records, want %d", len(all), workers+1)
+	}
+}
+
+func testClose(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	mustCreate(ctx, t, repo, newRecord("a.example.com"))
+
+	if err := repo.Close(); err != nil {
+		t.Fatalf("Close error: %v", err)
+	}
+	if err := repo.Close(); err != nil {
+		t.Fatalf("second Close error: %v", err)
+	}
+
+	_, err := repo.Find(ctx)
+	expectError(t, "Find after Close", err, types.ErrClosed)
+	expectError(t, "Create after Close", repo.Create(ctx, newRecord("b.example.com")), types.ErrClosed)
+}
+
+func testContext(ctx context.Context, t *testing.T, repo types.RecordRepository) {
+	mustCreate(ctx, t, repo, newRecord("a.example.com"))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	_, err := repo.Find(cancelled)
+	expectError(t, "Find(cancelled)", err, context.Canceled)
+	_, err = repo.FindByDomain(cancelled, "a.example.com")
+	expectError(t, "FindByDomain(cancelled)", err, context.Canceled)
+	expectError(t, "Create(cancelled)", repo.Create(cancelled, newRecord("b.example.com")), context.Canceled)
+	expectError(t, "Delete(cancelled)", repo.Delete(cancelled, "a.example.com"), context.Canceled)
+
+	// nothing changed
+	if _, err = repo.FindByDomain(ctx, "a.example.com"); err != nil {
+		t.Fatalf("FindByDomain error: %v", err)
+	}
+	_, err = repo.FindByDomain(ctx, "b.example.com")
+	expectError(t, "FindByDomain(b)", err, types.ErrNotFound)
+}
@@ -0,0 +1,47 @@
+package tsdns
+
+import (
+	"context"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+// Retarget moves the records of a host to another host during a migration
+//
+// Records pointing to from, or to any port of from.Target if from.Port is 0, are pointed to to.
+// An empty to.Target keeps the host and opts.PortShift shifts a port range instead of setting a port.
+// All matching records are changed or none is, and the cache is updated once at the end.
+// With opts.DryRun nothing is changed. It returns the diff ordered by record ID
+func (s *Server) Retarget(from, to types.Endpoint, opts types.RetargetOptions) ([]types.RecordDiff, error) {
+	return s.RetargetContext(context.Background(), from, to, opts)
+}
+
+// RetargetContext is like Retarget but uses the deadline, tenant and actor of ctx
+func (s *Server) RetargetContext(ctx context.Context, from, to types.Endpoint, opts types.RetargetOptions) ([]types.RecordDiff, error) {
+	if to.Target != "" {
+		if reason := checkTarget(to.Target); reason != "" {
+			verr := &ValidationError{}
+			verr.add("target", "%s", reason)
+			return nil, verr
+		}
+	}
+
+	ctx, cancel := s.repoContext(ctx)
+	defer cancel()
+
+	diffs, err := s.repository.Retarget(ctx, from, to, opts)
+	if err != nil || opts.DryRun {
+		return diffs, err
+	}
+
+	changes := make([]types.Change, len(diffs))
+	for i, diff := range diffs {
+		changes[i] = types.Change{Op: types.ChangeUpdate, Record: diff.After}
+	}
+	s.updateCache(s.tenant(ctx), changes...)
+
+	if len(diffs) > 0 {
+		s.logger.Info("Retargeted %d records from %s\n", len(diffs), from)
+	}
+	return diffs, nil
+}
@@ -5,24 +5,72 @@ import (
 	"fmt"
 	"github.com/honeybbq/tsdns-go/types"
 	"net"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// defaultRepositoryTimeout bounds a single repository operation
+const defaultRepositoryTimeout = 10 * time.Second
+
 // ServerBuilder represents a builder for TSDNS server
 type ServerBuilder struct {
 	server *Server
 	err    error
 }
 
+// Status describes whether the server cache is backed by a reachable repository
+type Status int32
+
+const (
+	// StatusHealthy means the cache was last refreshed from the repository
+	StatusHealthy Status = iota
+	// StatusDegraded means the repository is unreachable and stale data is being served
+	StatusDegraded
+)
+
+// String returns the human readable name of the status
+func (st Status) String() string {
+	switch st {
+	case StatusHealthy:
+		return "healthy"
+	case StatusDegraded:
+		return "degraded"
+	default:
+		return fmt.Sprintf("Status(%d)", int32(st))
+	}
+}
+
 // Server represents a TSDNS server instance
 type Server struct {
-	addr       string
-	repository types.RecordRepository
-	cache      map[string]*types.Record
-	mu         sync.RWMutex
-	ctx        context.Context
-	cancel     context.CancelFunc
-	logger     Logger
+	addr         string
+	repository   types.RecordRepository
+	cache        *recordCache
+	ctx          context.Context
+	cancel       context.CancelFunc
+	logger       Logger
+	snapshotPath string
+	status       atomic.Int32
+	passThrough  bool
+	upstream     string
+	answers      *answerCache
+	answerTTL    time.Duration
+	negativeTTL  time.Duration
+	reapInterval time.Duration
+	timeout      time.Duration
+	// purgeInterval enables the purge job when positive
+	purgeInterval  time.Duration
+	purgeRetention time.Duration
+	// maintenance answers for disabled records when its target is set, and for maintenance windows without a target
+	maintenance types.Endpoint
+	policy      *Policy
+	// policyMu serializes writes checked against domain quotas
+	policyMu sync.Mutex
+	// tenants are the served tenants in order of precedence, the first is the default
+	tenants []string
+	// ports is the range AllocatePort hands out, empty if not set
+	ports types.PortRange
 }
 
 // NewServer creates a new TSDNS server builder
@@ -31,11 +79,13 @@ func NewServer(ip string) *ServerBuilder {
 
 	builder := &ServerBuilder{
 		server: &Server{
-			addr:   ip + ":41144",
-			cache:  make(map[string]*types.Record),
-			ctx:    ctx,
-			cancel: cancel,
-			logger: newStdLogger(), // Default logger
+			addr:         ip + ":41144",
+			ctx:          ctx,
+			cancel:       cancel,
+			logger:       newStdLogger(), // Default logger
+			reapInterval: defaultReapInterval,
+			timeout:      defaultRepositoryTimeout,
+			tenants:      []string{""},
 		},
 	}
 
@@ -43,6 +93,7 @@ func NewServer(ip string) *ServerBuilder {
 	if ip != "0.0.0.0" && net.ParseIP(ip) == nil {
 		builder.err = fmt.Errorf("invalid IP address")
 	}
+	builder.server.cache = newRecordCache(builder.server.tenants, nil)
 
 	return builder
 }
@@ -65,6 +116,189 @@ func (b *ServerBuilder) WithLogger(l Logger) *ServerBuilder {
 	return b
 }
 
+// WithSnapshot enables persisting the cache to the given file
+//
+// The snapshot is rewritten after every successful cache refresh and is used
+// to serve records when the repository is unreachable on start
+func (b *ServerBuilder) WithSnapshot(path string) *ServerBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.server.snapshotPath = path
+	return b
+}
+
+// WithPassThrough makes cache misses fall through to a direct repository read
+//
+// This picks up records written by other processes before the next cache refresh
+func (b *ServerBuilder) WithPassThrough() *ServerBuilder {
+	if b.err != nil {
+		return b
+	}
+	b.server.passThrough = true
+	return b
+}
+
+// WithUpstream forwards queries that miss locally to another TSDNS server
+//
+// addr is the upstream address, the default TSDNS port is used if it has none
+func (b *ServerBuilder) WithUpstream(addr string) *ServerBuilder {
+	if b.err != nil {
+		return b
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "41144")
+	}
+	b.server.upstream = addr
+	return b
+}
+
+// WithAnswerCache caches misses and answers from pass-through and upstream lookups
+//
+// size is the maximum number of entries, ttl applies to found answers and
+// negativeTTL to misses
+func (b *ServerBuilder) WithAnswerCache(size int, ttl, negativeTTL time.Duration) *ServerBuilder {
+	if b.err != nil {
+		return b
+	}
+	if size <= 0 {
+		b.err = fmt.Errorf("answer cache size must be positive")
+		return b
+	}
+	b.server.answers = newAnswerCache(size)
+	b.server.answerTTL = ttl
+	b.server.negativeTTL = negativeTTL
+	return b
+}
+
+// WithReapInterval sets how often records with an expired lease are removed
+func (b *ServerBuilder) WithReapInterval(interval time.Duration) *ServerBuilder {
+	if b.err != nil {
+		return b
+	}
+	if interval <= 0 {
+		b.err = fmt.Errorf("reap interval must be positive")
+		return b
+	}
+	b.server.reapInterval = interval
+	return b
+}
+
+// WithPurge permanently removes soft-deleted records older than retention every interval
+//
+// Purged records can no longer be restored
+func (b *ServerBuilder) WithPurge(interval, retention time.Duration) *ServerBuilder {
+	if b.err != nil {
+		return b
+	}
+	if interval <= 0 {
+		b.err = fmt.Errorf("purge interval must be positive")
+		return b
+	}
+	if retention < 0 {
+		b.err = fmt.Errorf("purge retention must not be negative")
+		return b
+	}
+	b.server.purgeInterval = interval
+	b.server.purgeRetention = retention
+	return b
+}
+
+// WithMaintenanceTarget answers queries for disabled records with the given target
+//
+// It also answers for maintenance windows without a target, see SetMaintenance. Without
+// a maintenance target disabled records answer like a miss. port 0 leaves the port to
+// the client
+func (b *ServerBuilder) WithMaintenanceTarget(target string, port int32) *ServerBuilder {
+	if b.err != nil {
+		return b
+	}
+	if reason := checkTarget(target); reason != "" {
+		b.err = fmt.Errorf("maintenance target %s", reason)
+		return b
+	}
+	if port < 0 || port > maxPort {
+		b.err = fmt.Errorf("maintenance port %d is out of range 0-%d", port, maxPort)
+		return b
+	}
+	b.server.maintenance = types.Endpoint{Target: target, Port: port}
+	return b
+}
+
+// WithPolicy restricts the domains instances may claim with quotas and reserved patterns
+//
+// Every method storing a record checks it against the policy, violations are returned
+// as *QuotaError or *ReservedDomainError
+func (b *ServerBuilder) WithPolicy(policy Policy) *ServerBuilder {
+	if b.err != nil {
+		return b
+	}
+	if err := policy.validate(); err != nil {
+		b.err = err
+		return b
+	}
+	b.server.policy = &policy
+	return b
+}
+
+// WithPortRange sets the range of ports AllocatePort hands out, bounds included
+func (b *ServerBuilder) WithPortRange(min, max int32) *ServerBuilder {
+	if b.err != nil {
+		return b
+	}
+	ports := types.PortRange{Min: min, Max: max}
+	if err := ports.Validate(); err != nil {
+		b.err = err
+		return b
+	}
+	b.server.ports = ports
+	return b
+}
+
+// WithTenants serves the records of the given tenants, see types.WithTenant
+//
+// A domain held by several tenants answers with the record of the first tenant listed.
+// Record methods act on the tenant set on their context, or on the first tenant listed.
+// Methods reading the cache, such as SetRecordEnabled, only see the served tenants.
+// Without this option the default tenant, the empty string, is served
+func (b *ServerBuilder) WithTenants(tenants ...string) *ServerBuilder {
+	if b.err != nil {
+		return b
+	}
+	if len(tenants) == 0 {
+		b.err = fmt.Errorf("at least one tenant is required")
+		return b
+	}
+	for i, tenant := range tenants {
+		if len(tenant) > types.MaxTenantLength {
+			b.err = fmt.Errorf("tenant %q is longer than %d characters", tenant, types.MaxTenantLength)
+			return b
+		}
+		if slices.Contains(tenants[:i], tenant) {
+			b.err = fmt.Errorf("tenant %q is listed twice", tenant)
+			return b
+		}
+	}
+	b.server.tenants = slices.Clone(tenants)
+	b.server.cache = newRecordCache(b.server.tenants, nil)
+	return b
+}
+
+// WithRepositoryTimeout sets the maximum duration of a single repository operation
+//
+// It applies on top of any deadline of the context passed to the record methods
+func (b *ServerBuilder) WithRepositoryTimeout(timeout time.Duration) *ServerBuilder {
+	if b.err != nil {
+		return b
+	}
+	if timeout <= 0 {
+		b.err = fmt.Errorf("repository timeout must be positive")
+		return b
+	}
+	b.server.timeout = timeout
+	return b
+}
+
 // Build creates and returns the server instance
 func (b *ServerBuilder) Build() (*Server, error) {
 	if b.err != nil {
@@ -78,6 +312,12 @@ func (b *ServerBuilder) Build() (*Server, error) {
 
 	// Start cache updater
 	go b.server.cacheUpdater()
+	// Start expired record reaper
+	go b.server.reaper()
+	// Start deleted record purger
+	if b.server.purgeInterval > 0 {
+		go b.server.purger()
+	}
 
 	return b.server, nil
 }
@@ -92,7 +332,7 @@ func (b *ServerBuilder) MustBuild() *Server {
 }
 
 // Start initializes and runs the TSDNS server
-// It listens for incoming TCP connections and handles DNS queries
+// It listens for incoming TCP connections and handles DNS queries until Close is called
 func (s *Server) Start() error {
 	addr, err := net.ResolveTCPAddr("tcp", s.addr)
 	if err != nil {
@@ -104,10 +344,17 @@ func (s *Server) Start() error {
 		return fmt.Errorf("listen error: %v", err)
 	}
 	defer listener.Close()
+	stop := context.AfterFunc(s.ctx, func() {
+		listener.Close()
+	})
+	defer stop()
 
-	// load cache initially
+	// load cache initially, falling back to the snapshot if the repository is down
 	if err = s.loadCache(); err != nil {
-		return fmt.Errorf("load cache error: %v", err)
+		if _err := s.loadSnapshot(); _err != nil {
+			return fmt.Errorf("load cache error: %v", err)
+		}
+		s.logger.Warn("Repository unavailable, serving from snapshot in degraded mode: %v\n", err)
 	}
 
 	s.logger.Info("TSDNS server started at %s\n", s.addr)
@@ -115,6 +362,9 @@ func (s *Server) Start() error {
 	for {
 		conn, _err := listener.Accept()
 		if _err != nil {
+			if s.ctx.Err() != nil {
+				return nil
+			}
 			s.logger.Error("accept error: %v\n", _err)
 			continue
 		}
@@ -122,7 +372,38 @@ func (s *Server) Start() error {
 	}
 }
 
+// Status reports whether the server is serving fresh or stale records
+func (s *Server) Status() Status {
+	return Status(s.status.Load())
+}
+
+// setStatus updates the server status
+func (s *Server) setStatus(st Status) {
+	s.status.Store(int32(st))
+}
+
+// repoContext derives the context for a repository operation
+// It is done when ctx is done, when the server is closed or after the repository timeout,
+// and scoped to the default tenant if ctx has none
+func (s *Server) repoContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(types.WithTenant(ctx, s.tenant(ctx)), s.timeout)
+	stop := context.AfterFunc(s.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// tenant returns the tenant an operation with ctx acts on
+func (s *Server) tenant(ctx context.Context) string {
+	if tenant, ok := types.TenantFrom(ctx); ok {
+		return tenant
+	}
+	return s.tenants[0]
+}
+
 // Close shuts down the server and releases resources
+// Repository operations still in flight are cancelled
 func (s *Server) Close() error {
 	s.logger.Info("Shutting down tsdns-go server...")
 	s.cancel()
@@ -0,0 +1,41 @@
+package tsdns
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/honeybbq/tsdns-go/repository/file"
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+// newTestServer builds a server on an empty file repository
+// configure may set further options on the builder, such as a policy
+func newTestServer(t *testing.T, configure func(b *ServerBuilder) *ServerBuilder) *Server {
+	t.Helper()
+	repo, err := file.NewRepository(filepath.Join(t.TempDir(), "records.bin"))
+	if err != nil {
+		t.Fatalf("NewRepository error: %v", err)
+	}
+	builder := NewServer("127.0.0.1").WithRepository(repo)
+	if configure != nil {
+		builder = configure(builder)
+	}
+	s, err := builder.Build()
+	if err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+	t.Cleanup(func() {
+		s.Close()
+	})
+	return s
+}
+
+// mustCreate stores records through the server
+func mustCreate(t *testing.T, s *Server, records ...*types.Record) {
+	t.Helper()
+	for _, record := range records {
+		if err := s.CreateRecord(record); err != nil {
+			t.Fatalf("CreateRecord(%s) error: %v", record.Domain, err)
+		}
+	}
+}
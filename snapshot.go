@@ -0,0 +1,78 @@
+package tsdns
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/honeybbq/tsdns-go/types"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// snapshot is the on-disk representation of the record cache and the maintenance windows
+type snapshot struct {
+	SavedAt     time.Time
+	Records     []*types.Record
+	Maintenance []*types.Maintenance
+}
+
+// saveSnapshot writes the given records and maintenance windows to the snapshot file
+// The file is replaced atomically so a crash never leaves a partial snapshot behind
+func (s *Server) saveSnapshot(records []*types.Record, windows []*types.Maintenance) error {
+	if s.snapshotPath == "" {
+		return nil
+	}
+
+	snap := &snapshot{
+		SavedAt:     time.Now(),
+		Records:     records,
+		Maintenance: windows,
+	}
+	data, err := msgpack.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.snapshotPath), filepath.Base(s.snapshotPath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write snapshot: %v", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write snapshot: %v", err)
+	}
+
+	if err = os.Rename(tmp.Name(), s.snapshotPath); err != nil {
+		return fmt.Errorf("failed to replace snapshot: %v", err)
+	}
+	return nil
+}
+
+// loadSnapshot fills the cache from the snapshot file
+// It is used when the repository is unreachable on start
+func (s *Server) loadSnapshot() error {
+	if s.snapshotPath == "" {
+		return fmt.Errorf("no snapshot configured")
+	}
+
+	data, err := os.ReadFile(s.snapshotPath)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot: %v", err)
+	}
+
+	var snap snapshot
+	if err = msgpack.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %v", err)
+	}
+
+	s.cache.replace(snap.Records, snap.Maintenance)
+	s.logger.Info("Loaded %d records and %d maintenance windows from snapshot taken at %s\n",
+		len(snap.Records), len(snap.Maintenance), snap.SavedAt.Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,195 @@
+package tsdns
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/honeybbq/tsdns-go/repository/file"
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+// errUnavailable is returned by an unavailable flakyRepository
+var errUnavailable = errors.New("repository unavailable")
+
+// flakyRepository fails to read records while unavailable is set
+type flakyRepository struct {
+	types.RecordRepository
+	unavailable atomic.Bool
+}
+
+func (r *flakyRepository) Find(ctx context.Context) ([]*types.Record, error) {
+	if r.unavailable.Load() {
+		return nil, errUnavailable
+	}
+	return r.RecordRepository.Find(ctx)
+}
+
+// newFlakyServer builds a server with a snapshot file on an unavailable repository holding a.example.com
+func newFlakyServer(t *testing.T, snapshotPath string) (*Server, *flakyRepository) {
+	t.Helper()
+	inner, err := file.NewRepository(filepath.Join(t.TempDir(), "records.bin"))
+	if err != nil {
+		t.Fatalf("NewRepository error: %v", err)
+	}
+	ctx := context.Background()
+	if err = inner.Create(ctx, &types.Record{Domain: "a.example.com", Target: "192.0.2.1", Port: 9987}); err != nil {
+		t.Fatalf("Create error: %v", err)
+	}
+
+	repo := &flakyRepository{RecordRepository: inner}
+	repo.unavailable.Store(true)
+	s, err := NewServer("127.0.0.1").WithRepository(repo).WithSnapshot(snapshotPath).Build()
+	if err != nil {
+		t.Fatalf("Build error: %v", err)
+	}
+	t.Cleanup(func() {
+		s.Close()
+	})
+	return s, repo
+}
+
+// writeSnapshot saves a snapshot holding the given records
+func writeSnapshot(t *testing.T, path string, records ...*types.Record) {
+	t.Helper()
+	s := newTestServer(t, func(b *ServerBuilder) *ServerBuilder {
+		return b.WithSnapshot(path)
+	})
+	if err := s.saveSnapshot(records, nil); err != nil {
+		t.Fatalf("saveSnapshot error: %v", err)
+	}
+}
+
+// query asks a running server for a domain over TCP
+func query(addr, domain string) (string, error) {
+	conn, err := net.DialTimeout("tcp", addr, time.Second)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if err = conn.SetDeadline(time.Now().Add(time.Second)); err != nil {
+		return "", err
+	}
+	if _, err = conn.Write([]byte(domain)); err != nil {
+		return "", err
+	}
+	response, err := io.ReadAll(conn)
+	return strings.TrimSpace(string(response)), err
+}
+
+func TestStartFromSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.bin")
+	writeSnapshot(t, path, &types.Record{Domain: "stale.example.com", Target: "192.0.2.9", Port: 9987})
+	s, repo := newFlakyServer(t, path)
+
+	started := make(chan error, 1)
+	go func() {
+		started <- s.Start()
+	}()
+
+	// the server answers from the snapshot once it is listening
+	var response string
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		var err error
+		response, err = query(s.addr, "stale.example.com")
+		if err == nil && response != "404" {
+			break
+		}
+		select {
+		case err = <-started:
+			t.Fatalf("Start error: %v", err)
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server did not answer from the snapshot: %q, %v", response, err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if response != "192.0.2.9:9987" {
+		t.Fatalf("stale.example.com answered %q from the snapshot, want 192.0.2.9:9987", response)
+	}
+	if status := s.Status(); status != StatusDegraded {
+		t.Fatalf("Status = %v serving from the snapshot, want degraded", status)
+	}
+
+	// a successful reload replaces the snapshot with the repository
+	repo.unavailable.Store(false)
+	if err := s.loadCache(); err != nil {
+		t.Fatalf("loadCache error: %v", err)
+	}
+	if status := s.Status(); status != StatusHealthy {
+		t.Fatalf("Status = %v after a reload, want healthy", status)
+	}
+	mustResolve(t, s, "a.example.com", "192.0.2.1:9987")
+	mustResolve(t, s, "stale.example.com", "")
+
+	// the reload rewrote the snapshot
+	fresh, _ := newFlakyServer(t, path)
+	if err := fresh.loadSnapshot(); err != nil {
+		t.Fatalf("loadSnapshot error: %v", err)
+	}
+	mustResolve(t, fresh, "a.example.com", "192.0.2.1:9987")
+
+	s.Close()
+	select {
+	case err := <-started:
+		if err != nil {
+			t.Fatalf("Start error after Close: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Start did not return after Close")
+	}
+}
+
+func TestLoadSnapshotErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		// write prepares the snapshot file
+		write func(t *testing.T, path string)
+	}{
+		{"missing", func(t *testing.T, path string) {}},
+		{"corrupt", func(t *testing.T, path string) {
+			if err := os.WriteFile(path, []byte("not a snapshot"), 0o600); err != nil {
+				t.Fatalf("WriteFile error: %v", err)
+			}
+		}},
+		{"truncated", func(t *testing.T, path string) {
+			writeSnapshot(t, path, &types.Record{Domain: "a.example.com", Target: "192.0.2.1", Port: 9987})
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("ReadFile error: %v", err)
+			}
+			if err = os.WriteFile(path, data[:len(data)/2], 0o600); err != nil {
+				t.Fatalf("WriteFile error: %v", err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "snapshot.bin")
+			tt.write(t, path)
+			s, _ := newFlakyServer(t, path)
+			s.cache.replace([]*types.Record{{Domain: "cached.example.com", Target: "192.0.2.5", Port: 9987}}, nil)
+
+			// without records to serve the server does not start
+			if err := s.Start(); err == nil || !strings.Contains(err.Error(), errUnavailable.Error()) {
+				t.Fatalf("Start error = %v, want the repository error", err)
+			}
+			if err := s.loadSnapshot(); err == nil {
+				t.Fatalf("loadSnapshot succeeded on a %s snapshot", tt.name)
+			}
+			if status := s.Status(); status != StatusDegraded {
+				t.Fatalf("Status = %v, want degraded", status)
+			}
+			// the cache is left as it was
+			mustResolve(t, s, "cached.example.com", "192.0.2.5:9987")
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package tsdns
+
+import (
+	"context"
+	"testing"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+func TestServedTenants(t *testing.T) {
+	s := newTestServer(t, func(b *ServerBuilder) *ServerBuilder {
+		return b.WithTenants("brand-a", "shared")
+	})
+	shared := types.WithTenant(context.Background(), "shared")
+	other := types.WithTenant(context.Background(), "other")
+
+	// records without a tenant on their context go to the first tenant listed
+	if err := s.AddRecord("play.example.com", "192.0.2.1", 9987); err != nil {
+		t.Fatalf("AddRecord error: %v", err)
+	}
+	if err := s.AddRecordContext(shared, "play.example.com", "192.0.2.2", 9987); err != nil {
+		t.Fatalf("AddRecord in shared error: %v", err)
+	}
+	if err := s.AddRecordContext(shared, "status.example.com", "192.0.2.3", 9987); err != nil {
+		t.Fatalf("AddRecord in shared error: %v", err)
+	}
+	// aliases resolve within their own tenant
+	if err := s.AddAliasContext(shared, "ts.example.com", "play.example.com"); err != nil {
+		t.Fatalf("AddAlias in shared error: %v", err)
+	}
+	if err := s.AddRecordContext(other, "hidden.example.com", "192.0.2.4", 9987); err != nil {
+		t.Fatalf("AddRecord in other error: %v", err)
+	}
+
+	check := func() {
+		t.Helper()
+		mustResolve(t, s, "play.example.com", "192.0.2.1:9987")
+		mustResolve(t, s, "status.example.com", "192.0.2.3:9987")
+		mustResolve(t, s, "ts.example.com", "192.0.2.2:9987")
+		mustResolve(t, s, "hidden.example.com", "")
+	}
+	check()
+
+	// a reload from the repository keeps the precedence
+	if err := s.loadCache(); err != nil {
+		t.Fatalf("loadCache error: %v", err)
+	}
+	check()
+
+	// once the first tenant drops the domain, the next one answers for it
+	if err := s.RemoveRecord("play.example.com"); err != nil {
+		t.Fatalf("RemoveRecord error: %v", err)
+	}
+	mustResolve(t, s, "play.example.com", "192.0.2.2:9987")
+
+	// the records of the tenant not served are stored all the same
+	records, err := s.FindRecordsByTargetContext(other, "192.0.2.4", false)
+	if err != nil {
+		t.Fatalf("FindRecordsByTarget in other error: %v", err)
+	}
+	if len(records) != 1 || records[0].Tenant != "other" {
+		t.Fatalf("FindRecordsByTarget in other = %v, want hidden.example.com", records)
+	}
+}
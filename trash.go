@@ -0,0 +1,123 @@
+package tsdns
+
+import (
+	"context"
+	"time"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+// ListDeletedRecords returns all soft-deleted records
+func (s *Server) ListDeletedRecords() ([]*types.Record, error) {
+	return s.ListDeletedRecordsContext(context.Background())
+}
+
+// ListDeletedRecordsContext is like ListDeletedRecords but uses the deadline, tenant and actor of ctx
+func (s *Server) ListDeletedRecordsContext(ctx context.Context) ([]*types.Record, error) {
+	ctx, cancel := s.repoContext(ctx)
+	defer cancel()
+
+	return s.repository.ListDeleted(ctx)
+}
+
+// RestoreRecord undeletes the records of a domain removed by its most recent deletion
+// The records are checked with ValidateRecord, the policy set with WithPolicy and for alias
+// loops like records being stored, since the rules may have changed since they were deleted.
+// Restored records answer queries again immediately
+func (s *Server) RestoreRecord(domain string) ([]*types.Record, error) {
+	return s.RestoreRecordContext(context.Background(), domain)
+}
+
+// RestoreRecordContext is like RestoreRecord but uses the deadline, tenant and actor of ctx
+func (s *Server) RestoreRecordContext(ctx context.Context, domain string) ([]*types.Record, error) {
+	ctx, cancel := s.repoContext(ctx)
+	defer cancel()
+
+	deleted, err := s.repository.ListDeleted(ctx)
+	if err != nil {
+		return nil, err
+	}
+	changes := restoreChanges(deleted, domain)
+	if err = validateChanges(changes); err != nil {
+		return nil, err
+	}
+	if err = s.checkAliases(s.tenant(ctx), changes); err != nil {
+		return nil, err
+	}
+	unlock, err := s.enforcePolicy(ctx, changes)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	records, err := s.repository.Restore(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	changes = make([]types.Change, len(records))
+	for i, record := range records {
+		changes[i] = types.Change{Op: types.ChangeUpsert, Record: record}
+	}
+	s.updateCache(s.tenant(ctx), changes...)
+	return records, nil
+}
+
+// restoreChanges returns the changes restoring the records of a domain removed by its most recent deletion
+func restoreChanges(deleted []*types.Record, domain string) []types.Change {
+	var latest *time.Time
+	for _, record := range deleted {
+		if record.Domain == domain && record.DeletedAt != nil && (latest == nil || record.DeletedAt.After(*latest)) {
+			latest = record.DeletedAt
+		}
+	}
+
+	var changes []types.Change
+	for _, record := range deleted {
+		if record.Domain == domain && record.DeletedAt != nil && record.DeletedAt.Equal(*latest) {
+			restored := record.Clone()
+			restored.DeletedAt = nil
+			changes = append(changes, types.Change{Op: types.ChangeUpsert, Record: restored})
+		}
+	}
+	return changes
+}
+
+// PurgeRecords permanently removes records soft-deleted before the given time
+// It returns the number of purged records
+func (s *Server) PurgeRecords(olderThan time.Time) (int64, error) {
+	return s.PurgeRecordsContext(context.Background(), olderThan)
+}
+
+// PurgeRecordsContext is like PurgeRecords but uses the deadline, tenant and actor of ctx
+func (s *Server) PurgeRecordsContext(ctx context.Context, olderThan time.Time) (int64, error) {
+	ctx, cancel := s.repoContext(ctx)
+	defer cancel()
+
+	return s.repository.Purge(ctx, olderThan)
+}
+
+// purger periodically purges records deleted longer than the retention ago
+func (s *Server) purger() {
+	ticker := time.NewTicker(s.purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			olderThan := time.Now().Add(-s.purgeRetention)
+			for _, tenant := range s.tenants {
+				count, err := s.PurgeRecordsContext(types.WithTenant(s.ctx, tenant), olderThan)
+				if err != nil {
+					s.logger.Error("Purge deleted records error: %v\n", err)
+					continue
+				}
+				if count > 0 {
+					s.logger.Info("Purged %d deleted records\n", count)
+				}
+			}
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
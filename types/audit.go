@@ -0,0 +1,60 @@
+package types
+
+import (
+	"context"
+	"time"
+)
+
+// AuditAction identifies the kind of change recorded in an audit entry
+type AuditAction string
+
+const (
+	// AuditCreate records a created record, AuditEntry.Old is nil
+	AuditCreate AuditAction = "create"
+	// AuditUpdate records an updated or retargeted record
+	AuditUpdate AuditAction = "update"
+	// AuditDelete records a deleted record, AuditEntry.New is nil
+	AuditDelete AuditAction = "delete"
+	// AuditRestore records a restored record, AuditEntry.Old is the deleted record
+	AuditRestore AuditAction = "restore"
+)
+
+// AuditEntry is a single change of a record
+type AuditEntry struct {
+	// ID is unique within the repository, entries are rolled back to by ID
+	ID       int64
+	Tenant   string
+	RecordID int64
+	Domain   string
+	Action   AuditAction
+	// Actor is who made the change, as set with WithActor, empty if unknown
+	Actor string
+	Time  time.Time
+	// Old is the record before the change
+	Old *Record
+	// New is the record after the change
+	New *Record
+}
+
+// AuditRepository is implemented by repositories keeping an audit trail
+//
+// Such repositories record an entry for every record they create, update, retarget,
+// delete or restore, in the same operation as the change itself. Lease renewals
+// are not recorded, and the entries of a record are removed when it is purged
+type AuditRepository interface {
+	// History retrieves the audit entries of a domain, oldest first
+	History(ctx context.Context, domain string) ([]*AuditEntry, error)
+}
+
+type actorKey struct{}
+
+// WithActor returns a context attributing the changes made with it to actor in the audit trail
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor set with WithActor, or an empty string
+func ActorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
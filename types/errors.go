@@ -0,0 +1,87 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by RecordRepository implementations
+//
+// Implementations map their storage specific errors to these so callers can
+// check them with errors.Is regardless of the backend
+var (
+	// ErrNotFound is returned when no live record matches
+	ErrNotFound = errors.New("record not found")
+
+	// ErrConflict is returned when a change conflicts with the stored records
+	ErrConflict = errors.New("record conflict")
+
+	// ErrDomainExists is returned when creating a record for a domain and validity window
+	// that already has a live record, it matches ErrConflict
+	ErrDomainExists = fmt.Errorf("%w: domain already exists", ErrConflict)
+
+	// ErrVersionConflict is returned when an update carries an outdated record version,
+	// it matches ErrConflict. Implementations return it as a *VersionConflictError
+	ErrVersionConflict = fmt.Errorf("%w: record version mismatch", ErrConflict)
+
+	// ErrInvalidRecord is returned when a record cannot be stored as given
+	ErrInvalidRecord = errors.New("invalid record")
+
+	// ErrAliasLoop is returned when following the aliases of a record leads back to it or
+	// goes on for too long, it matches ErrInvalidRecord
+	ErrAliasLoop = fmt.Errorf("%w: alias loop", ErrInvalidRecord)
+
+	// ErrInvalidMaintenance is returned when a maintenance window cannot be stored as given
+	ErrInvalidMaintenance = errors.New("invalid maintenance window")
+
+	// ErrNoFreePort is returned when every port of a range is reserved or used by a record
+	ErrNoFreePort = errors.New("no free port")
+
+	// ErrInvalidQuery is returned when list options cannot be applied
+	ErrInvalidQuery = errors.New("invalid query")
+
+	// ErrClosed is returned when the repository is used after Close
+	ErrClosed = errors.New("repository closed")
+
+	// ErrNotSupported is returned when a repository does not implement an operation
+	ErrNotSupported = errors.New("operation not supported")
+)
+
+// VersionConflictError is returned when an update carries a version other than the stored one
+// It matches ErrVersionConflict and ErrConflict
+type VersionConflictError struct {
+	ID     int64
+	Domain string
+	// Expected is the version carried by the update
+	Expected int64
+	// Actual is the stored version
+	Actual int64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("record %d for %s has version %d, update expects %d", e.ID, e.Domain, e.Actual, e.Expected)
+}
+
+func (e *VersionConflictError) Unwrap() error {
+	return ErrVersionConflict
+}
+
+// Validate checks that the record can be stored
+// It returns an error matching ErrInvalidRecord describing the first problem found
+func (r *Record) Validate() error {
+	switch {
+	case r == nil:
+		return fmt.Errorf("%w: record is nil", ErrInvalidRecord)
+	case r.Domain == "":
+		return fmt.Errorf("%w: domain is empty", ErrInvalidRecord)
+	case r.Target == "" && r.AliasOf == "":
+		return fmt.Errorf("%w: target is empty", ErrInvalidRecord)
+	case r.AliasOf != "" && (r.Target != "" || r.Port != 0):
+		return fmt.Errorf("%w: alias has a target", ErrInvalidRecord)
+	case r.AliasOf == r.Domain:
+		return fmt.Errorf("%w: domain is an alias of itself", ErrInvalidRecord)
+	case r.ValidFrom != nil && r.ValidUntil != nil && !r.ValidUntil.After(*r.ValidFrom):
+		return fmt.Errorf("%w: validity window ends before it starts", ErrInvalidRecord)
+	}
+	return nil
+}
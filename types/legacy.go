@@ -0,0 +1,282 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// LegacyRecordRepository is the record storage interface from before repository
+// methods took a context
+//
+// Existing implementations keep working through FromLegacy
+type LegacyRecordRepository interface {
+	// Find retrieves all records
+	Find() ([]*Record, error)
+
+	// FindByDomain finds the record currently answering for a domain name
+	FindByDomain(domain string) (*Record, error)
+
+	// Create creates a new record
+	// It returns ErrDomainExists if a live record with the same domain and validity window exists
+	Create(record *Record) error
+
+	// Delete removes all records of a domain, including scheduled ones
+	Delete(domain string) error
+
+	// DeleteByInstanceID removes all records for a specific instance
+	DeleteByInstanceID(instanceID int64) error
+
+	// Close closes the storage connection
+	Close() error
+}
+
+// The methods added to repositories after LegacyRecordRepository, FromLegacy uses
+// them when the legacy implementation has them
+type (
+	legacyUpdater interface {
+		Update(record *Record) error
+	}
+	legacyUpserter interface {
+		Upsert(record *Record) error
+	}
+	legacyIDDeleter interface {
+		DeleteByID(id int64) error
+	}
+	legacyRenewer interface {
+		Renew(domain string, expiresAt time.Time) error
+	}
+	legacyInstanceRenewer interface {
+		RenewByInstanceID(instanceID int64, expiresAt time.Time) error
+	}
+	legacyExpirer interface {
+		DeleteExpired(now time.Time) (int64, error)
+	}
+)
+
+// FromLegacy adapts a repository without context support to RecordRepository
+//
+// Calls return ctx.Err() as soon as the context is done. The wrapped call cannot be
+// interrupted and keeps running in the background until it returns.
+// The legacy implementation only holds the default tenant, calls for any other tenant
+// return ErrNotSupported. Its "not found" errors and missing records are reported as ErrNotFound.
+// Update, Upsert, DeleteByID, Renew, RenewByInstanceID and DeleteExpired are passed on
+// when the legacy implementation has them with the same signature minus the context.
+// Other operations return ErrNotSupported
+func FromLegacy(repo LegacyRecordRepository) RecordRepository {
+	return &legacyRepository{repo: repo}
+}
+
+type legacyRepository struct {
+	repo LegacyRecordRepository
+}
+
+// call runs fn and waits for it until ctx is done
+func call[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	if tenant, _ := TenantFrom(ctx); tenant != "" {
+		return zero, fmt.Errorf("%w: legacy repository cannot store tenant %q", ErrNotSupported, tenant)
+	}
+	if ctx.Done() == nil {
+		value, err := fn()
+		return value, legacyError(err)
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := fn()
+		done <- result{value, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.value, legacyError(r.err)
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// legacyError maps the "not found" errors of legacy implementations, which predate
+// the sentinel errors, to ErrNotFound
+func legacyError(err error) error {
+	if err == nil || errors.Is(err, ErrNotFound) || !strings.Contains(strings.ToLower(err.Error()), "not found") {
+		return err
+	}
+	return fmt.Errorf("%w: %v", ErrNotFound, err)
+}
+
+// exec runs fn and waits for it until ctx is done
+func exec(ctx context.Context, fn func() error) error {
+	_, err := call(ctx, func() (struct{}, error) {
+		return struct{}{}, fn()
+	})
+	return err
+}
+
+func (l *legacyRepository) Find(ctx context.Context) ([]*Record, error) {
+	return call(ctx, l.repo.Find)
+}
+
+func (l *legacyRepository) List(ctx context.Context, opts ListOptions) (*RecordPage, error) {
+	records, err := l.Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ListRecords(records, opts)
+}
+
+func (l *legacyRepository) FindByDomain(ctx context.Context, domain string) (*Record, error) {
+	return call(ctx, func() (*Record, error) {
+		record, err := l.repo.FindByDomain(domain)
+		if err == nil && record == nil {
+			return nil, ErrNotFound
+		}
+		return record, err
+	})
+}
+
+func (l *legacyRepository) FindByTarget(ctx context.Context, target string, includeDeleted bool) ([]*Record, error) {
+	return l.findLive(ctx, includeDeleted, func(r *Record) bool {
+		return r.Target == target
+	})
+}
+
+func (l *legacyRepository) FindByInstanceID(ctx context.Context, instanceID int64, includeDeleted bool) ([]*Record, error) {
+	return l.findLive(ctx, includeDeleted, func(r *Record) bool {
+		return r.InstanceID == instanceID
+	})
+}
+
+// findLive filters the records returned by Find, which never include deleted ones
+func (l *legacyRepository) findLive(ctx context.Context, includeDeleted bool, match func(r *Record) bool) ([]*Record, error) {
+	if includeDeleted {
+		return nil, ErrNotSupported
+	}
+	records, err := l.Find(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []*Record
+	for _, r := range records {
+		if match(r) {
+			matched = append(matched, r)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].ID < matched[j].ID
+	})
+	return matched, nil
+}
+
+func (l *legacyRepository) Create(ctx context.Context, record *Record) error {
+	return exec(ctx, func() error {
+		return l.repo.Create(record)
+	})
+}
+
+func (l *legacyRepository) Update(ctx context.Context, record *Record) error {
+	repo, ok := l.repo.(legacyUpdater)
+	if !ok {
+		return ErrNotSupported
+	}
+	return exec(ctx, func() error {
+		return repo.Update(record)
+	})
+}
+
+func (l *legacyRepository) Upsert(ctx context.Context, record *Record) error {
+	repo, ok := l.repo.(legacyUpserter)
+	if !ok {
+		return ErrNotSupported
+	}
+	return exec(ctx, func() error {
+		return repo.Upsert(record)
+	})
+}
+
+func (l *legacyRepository) Delete(ctx context.Context, domain string) error {
+	return exec(ctx, func() error {
+		return l.repo.Delete(domain)
+	})
+}
+
+func (l *legacyRepository) DeleteByID(ctx context.Context, id int64) error {
+	repo, ok := l.repo.(legacyIDDeleter)
+	if !ok {
+		return ErrNotSupported
+	}
+	return exec(ctx, func() error {
+		return repo.DeleteByID(id)
+	})
+}
+
+func (l *legacyRepository) DeleteByInstanceID(ctx context.Context, instanceID int64) error {
+	return exec(ctx, func() error {
+		return l.repo.DeleteByInstanceID(instanceID)
+	})
+}
+
+func (l *legacyRepository) Renew(ctx context.Context, domain string, expiresAt time.Time) error {
+	repo, ok := l.repo.(legacyRenewer)
+	if !ok {
+		return ErrNotSupported
+	}
+	return exec(ctx, func() error {
+		return repo.Renew(domain, expiresAt)
+	})
+}
+
+func (l *legacyRepository) RenewByInstanceID(ctx context.Context, instanceID int64, expiresAt time.Time) error {
+	repo, ok := l.repo.(legacyInstanceRenewer)
+	if !ok {
+		return ErrNotSupported
+	}
+	return exec(ctx, func() error {
+		return repo.RenewByInstanceID(instanceID, expiresAt)
+	})
+}
+
+func (l *legacyRepository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	repo, ok := l.repo.(legacyExpirer)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	return call(ctx, func() (int64, error) {
+		return repo.DeleteExpired(now)
+	})
+}
+
+func (l *legacyRepository) Apply(context.Context, []Change) error {
+	return ErrNotSupported
+}
+
+func (l *legacyRepository) Retarget(context.Context, Endpoint, Endpoint, RetargetOptions) ([]RecordDiff, error) {
+	return nil, ErrNotSupported
+}
+
+func (l *legacyRepository) ListDeleted(context.Context) ([]*Record, error) {
+	return nil, ErrNotSupported
+}
+
+func (l *legacyRepository) Restore(context.Context, string) ([]*Record, error) {
+	return nil, ErrNotSupported
+}
+
+func (l *legacyRepository) Purge(context.Context, time.Time) (int64, error) {
+	return 0, ErrNotSupported
+}
+
+func (l *legacyRepository) Close() error {
+	return l.repo.Close()
+}
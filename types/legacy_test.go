@@ -0,0 +1,70 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// stubLegacy is a legacy repository reporting missing records the way implementations
+// written before the sentinel errors did
+type stubLegacy struct {
+	LegacyRecordRepository
+	records map[string]*Record
+}
+
+func (s *stubLegacy) FindByDomain(domain string) (*Record, error) {
+	return s.records[domain], nil
+}
+
+func (s *stubLegacy) Delete(domain string) error {
+	if s.records[domain] == nil {
+		return errors.New("record not found")
+	}
+	delete(s.records, domain)
+	return nil
+}
+
+func TestFromLegacy(t *testing.T) {
+	repo := FromLegacy(&stubLegacy{records: map[string]*Record{
+		"a.example.com": {ID: 1, Domain: "a.example.com", Target: "192.0.2.1"},
+	}})
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+		want error
+	}{
+		{"find existing", func() error {
+			_, err := repo.FindByDomain(ctx, "a.example.com")
+			return err
+		}, nil},
+		{"find missing", func() error {
+			_, err := repo.FindByDomain(ctx, "b.example.com")
+			return err
+		}, ErrNotFound},
+		{"delete missing", func() error {
+			return repo.Delete(ctx, "b.example.com")
+		}, ErrNotFound},
+		{"default tenant", func() error {
+			_, err := repo.FindByDomain(WithTenant(ctx, ""), "a.example.com")
+			return err
+		}, nil},
+		{"other tenant", func() error {
+			_, err := repo.FindByDomain(WithTenant(ctx, "other"), "a.example.com")
+			return err
+		}, ErrNotSupported},
+		{"unsupported operation", func() error {
+			return repo.Update(ctx, &Record{Domain: "a.example.com"})
+		}, ErrNotSupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package types
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SortField selects the order of listed records
+type SortField int
+
+const (
+	// SortByID orders records by ID, which is the creation order
+	SortByID SortField = iota
+	// SortByDomain orders records by domain name
+	SortByDomain
+	// SortByCreatedAt orders records by creation time
+	SortByCreatedAt
+)
+
+// ListOptions filters, sorts and paginates live records
+//
+// Empty filters match every record. Records with the same sort key are ordered by ID
+type ListOptions struct {
+	// InstanceID only matches records of this instance, 0 matches any instance
+	InstanceID int64
+	// DomainPrefix only matches domains starting with the prefix
+	DomainPrefix string
+	// DomainContains only matches domains containing the substring
+	DomainContains string
+	// Target only matches records pointing to this host
+	Target string
+	// Owner only matches records of this owner
+	Owner string
+	// AliasOf only matches aliases of this domain
+	AliasOf string
+	// Labels only matches records carrying every one of these labels with the same value
+	Labels map[string]string
+	// Disabled only matches disabled records if true and enabled records if false, nil matches both
+	Disabled *bool
+
+	Sort       SortField
+	Descending bool
+
+	// Limit is the maximum number of records per page, 0 returns all records
+	Limit int
+	// Cursor continues after the page that returned it as RecordPage.NextCursor
+	Cursor string
+}
+
+// RecordPage is a page of listed records
+type RecordPage struct {
+	Records []*Record
+	// NextCursor fetches the next page, it is empty on the last page
+	NextCursor string
+}
+
+// Cursor is the decoded position after the last record of a page
+type Cursor struct {
+	ID        int64     `json:"i"`
+	Domain    string    `json:"d,omitempty"`
+	CreatedAt time.Time `json:"c,omitempty"`
+}
+
+// CursorAfter returns the cursor continuing after the given record
+func CursorAfter(r *Record) Cursor {
+	return Cursor{ID: r.ID, Domain: r.Domain, CreatedAt: r.CreatedAt}
+}
+
+// Encode returns the opaque representation used in ListOptions.Cursor
+func (c Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Validate checks the sort field and decodes the cursor, if any
+// It returns an error matching ErrInvalidQuery for unknown sort fields and malformed cursors
+func (o ListOptions) Validate() (*Cursor, error) {
+	switch {
+	case o.Sort < SortByID || o.Sort > SortByCreatedAt:
+		return nil, fmt.Errorf("%w: unknown sort field %d", ErrInvalidQuery, o.Sort)
+	case o.Limit < 0:
+		return nil, fmt.Errorf("%w: negative limit", ErrInvalidQuery)
+	case o.Cursor == "":
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(o.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	var cursor Cursor
+	if err = json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	return &cursor, nil
+}
+
+// Match reports whether the record passes the filters
+func (o ListOptions) Match(r *Record) bool {
+	switch {
+	case o.InstanceID != 0 && r.InstanceID != o.InstanceID:
+		return false
+	case o.DomainPrefix != "" && !strings.HasPrefix(r.Domain, o.DomainPrefix):
+		return false
+	case o.DomainContains != "" && !strings.Contains(r.Domain, o.DomainContains):
+		return false
+	case o.Target != "" && r.Target != o.Target:
+		return false
+	case o.Owner != "" && r.Owner != o.Owner:
+		return false
+	case o.AliasOf != "" && r.AliasOf != o.AliasOf:
+		return false
+	case o.Disabled != nil && r.Disabled != *o.Disabled:
+		return false
+	}
+	for key, value := range o.Labels {
+		if label, ok := r.Labels[key]; !ok || label != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Less reports whether a is listed before b
+func (o ListOptions) Less(a, b *Record) bool {
+	if o.Descending {
+		a, b = b, a
+	}
+	switch o.Sort {
+	case SortByDomain:
+		if a.Domain != b.Domain {
+			return a.Domain < b.Domain
+		}
+	case SortByCreatedAt:
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+	}
+	return a.ID < b.ID
+}
+
+// ListRecords filters, sorts and paginates records in memory
+// Backends without an indexed query can implement RecordRepository.List with it
+func ListRecords(records []*Record, opts ListOptions) (*RecordPage, error) {
+	cursor, err := opts.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var after *Record
+	if cursor != nil {
+		after = &Record{ID: cursor.ID, Domain: cursor.Domain, CreatedAt: cursor.CreatedAt}
+	}
+
+	matched := make([]*Record, 0, len(records))
+	for _, r := range records {
+		if opts.Match(r) && (after == nil || opts.Less(after, r)) {
+			matched = append(matched, r)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return opts.Less(matched[i], matched[j])
+	})
+
+	return NewRecordPage(matched, opts.Limit), nil
+}
+
+// NewRecordPage builds a page from sorted records
+// records may hold one record more than limit to signal that another page follows
+func NewRecordPage(records []*Record, limit int) *RecordPage {
+	if limit <= 0 || len(records) <= limit {
+		return &RecordPage{Records: records}
+	}
+	records = records[:limit]
+	return &RecordPage{
+		Records:    records,
+		NextCursor: CursorAfter(records[limit-1]).Encode(),
+	}
+}
@@ -0,0 +1,117 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// MaintenanceKind identifies what a maintenance window applies to
+type MaintenanceKind string
+
+const (
+	// MaintenanceGlobal redirects every query of the server
+	MaintenanceGlobal MaintenanceKind = "global"
+	// MaintenanceInstance redirects queries answered by records of an instance
+	MaintenanceInstance MaintenanceKind = "instance"
+	// MaintenanceDomain redirects queries for a domain
+	MaintenanceDomain MaintenanceKind = "domain"
+)
+
+// MaintenanceScope selects the queries a maintenance window redirects
+type MaintenanceScope struct {
+	Kind MaintenanceKind
+	// InstanceID is the instance of a MaintenanceInstance scope
+	InstanceID int64
+	// Domain is the domain of a MaintenanceDomain scope
+	Domain string
+}
+
+// GlobalScope returns the scope of every query
+func GlobalScope() MaintenanceScope {
+	return MaintenanceScope{Kind: MaintenanceGlobal}
+}
+
+// InstanceScope returns the scope of the queries answered by records of an instance
+func InstanceScope(instanceID int64) MaintenanceScope {
+	return MaintenanceScope{Kind: MaintenanceInstance, InstanceID: instanceID}
+}
+
+// DomainScope returns the scope of the queries for a domain
+func DomainScope(domain string) MaintenanceScope {
+	return MaintenanceScope{Kind: MaintenanceDomain, Domain: domain}
+}
+
+// String returns a human readable description of the scope
+func (s MaintenanceScope) String() string {
+	switch s.Kind {
+	case MaintenanceInstance:
+		return fmt.Sprintf("instance %d", s.InstanceID)
+	case MaintenanceDomain:
+		return "domain " + s.Domain
+	default:
+		return string(s.Kind)
+	}
+}
+
+// Maintenance is a window during which the queries of a scope are redirected to a maintenance server
+type Maintenance struct {
+	ID     int64
+	Tenant string
+	Scope  MaintenanceScope
+	// Target is where queries are redirected, an empty target uses the default of the server
+	Target Endpoint
+	// From and Until bound the window, nil leaves that side open
+	From      *time.Time
+	Until     *time.Time
+	CreatedAt time.Time
+}
+
+// ActiveAt reports whether the window redirects queries at the given time
+func (m *Maintenance) ActiveAt(now time.Time) bool {
+	return (m.From == nil || !m.From.After(now)) && !m.Ended(now)
+}
+
+// Ended reports whether the window ended at or before the given time
+func (m *Maintenance) Ended(now time.Time) bool {
+	return m.Until != nil && !m.Until.After(now)
+}
+
+// Validate checks that the window can be stored
+// It returns an error matching ErrInvalidMaintenance
+func (m *Maintenance) Validate() error {
+	switch {
+	case m == nil:
+		return fmt.Errorf("%w: maintenance is nil", ErrInvalidMaintenance)
+	case m.Scope.Kind == MaintenanceInstance && m.Scope.InstanceID == 0:
+		return fmt.Errorf("%w: instance scope without instance", ErrInvalidMaintenance)
+	case m.Scope.Kind == MaintenanceDomain && m.Scope.Domain == "":
+		return fmt.Errorf("%w: domain scope without domain", ErrInvalidMaintenance)
+	case m.Scope.Kind != MaintenanceGlobal && m.Scope.Kind != MaintenanceInstance && m.Scope.Kind != MaintenanceDomain:
+		return fmt.Errorf("%w: unknown scope %q", ErrInvalidMaintenance, m.Scope.Kind)
+	case m.Target.Port < 0 || m.Target.Port > 65535:
+		return fmt.Errorf("%w: port %d is out of range 0-65535", ErrInvalidMaintenance, m.Target.Port)
+	case m.From != nil && m.Until != nil && !m.Until.After(*m.From):
+		return fmt.Errorf("%w: window ends before it starts", ErrInvalidMaintenance)
+	}
+	return nil
+}
+
+// MaintenanceRepository is implemented by repositories that persist maintenance windows
+type MaintenanceRepository interface {
+	// FindMaintenance retrieves the maintenance windows of the tenant of ctx ordered by ID
+	FindMaintenance(ctx context.Context) ([]*Maintenance, error)
+
+	// CreateMaintenance stores a maintenance window in the tenant of ctx
+	// It sets the ID, tenant and creation time of the window
+	CreateMaintenance(ctx context.Context, m *Maintenance) error
+
+	// DeleteMaintenance removes a maintenance window of the tenant of ctx
+	// It returns ErrNotFound if there is none with the ID
+	DeleteMaintenance(ctx context.Context, id int64) error
+
+	// ReplaceMaintenance removes the windows of the scope of m running at now and stores m
+	// in the tenant of ctx, in a single operation so concurrent replacements leave one window
+	// running. It sets the ID, tenant and creation time of m and returns the IDs of the removed windows
+	ReplaceMaintenance(ctx context.Context, m *Maintenance, now time.Time) ([]int64, error)
+}
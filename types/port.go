@@ -0,0 +1,52 @@
+package types
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// PortRange is an inclusive range of ports
+type PortRange struct {
+	Min, Max int32
+}
+
+// Validate checks that the range is non-empty and within 1-65535
+func (r PortRange) Validate() error {
+	if r.Min < 1 || r.Max > 65535 {
+		return fmt.Errorf("%w: port range %s is not within 1-65535", ErrInvalidQuery, r)
+	}
+	if r.Max < r.Min {
+		return fmt.Errorf("%w: port range %s ends before it starts", ErrInvalidQuery, r)
+	}
+	return nil
+}
+
+// String returns the range as min-max
+func (r PortRange) String() string {
+	return fmt.Sprintf("%d-%d", r.Min, r.Max)
+}
+
+// PortReservation is a port of a host held for an instance
+type PortReservation struct {
+	Tenant     string
+	InstanceID int64
+	Target     string
+	Port       int32
+	CreatedAt  time.Time
+}
+
+// PortRepository is implemented by repositories that can reserve ports of a host
+//
+// A port is a resource of the host rather than of a tenant, so a reserved port, or one
+// a live record of any tenant points to, is not handed out again in any tenant
+type PortRepository interface {
+	// ReservePort reserves the lowest free port of target within ports for an instance of the tenant of ctx
+	// Concurrent reservations never return the same port. It returns ErrNoFreePort if the range is
+	// exhausted and ErrInvalidQuery if it is invalid
+	ReservePort(ctx context.Context, target string, ports PortRange, instanceID int64) (int32, error)
+
+	// ReleasePorts releases the ports reserved for an instance of the tenant of ctx
+	// It returns the number of released ports
+	ReleasePorts(ctx context.Context, instanceID int64) (int64, error)
+}
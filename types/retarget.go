@@ -0,0 +1,101 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Endpoint is a host and port records point to
+type Endpoint struct {
+	Target string
+	// Port 0 matches any port in a retarget source and keeps the port in a destination
+	Port int32
+}
+
+// String returns the endpoint as host:port, or the host alone without a port
+func (e Endpoint) String() string {
+	if e.Port == 0 {
+		return e.Target
+	}
+	return fmt.Sprintf("%s:%d", e.Target, e.Port)
+}
+
+// RetargetOptions narrows down and shapes a retarget
+type RetargetOptions struct {
+	// InstanceID only retargets records of this instance, 0 matches any instance
+	InstanceID int64
+	// DomainPrefix only retargets domains starting with the prefix
+	DomainPrefix string
+	// PortMin and PortMax only retarget records within the port range, 0 leaves a bound open
+	PortMin, PortMax int32
+	// PortShift is added to the port of every retargeted record, the destination port must be 0
+	PortShift int32
+	// DryRun computes the diff without changing any record
+	DryRun bool
+}
+
+// RecordDiff is the state of a record before and after a change
+type RecordDiff struct {
+	Before *Record
+	After  *Record
+}
+
+// ValidateRetarget checks that a retarget from one endpoint to another is well formed
+// It returns an error matching ErrInvalidQuery describing the first problem found
+func ValidateRetarget(from, to Endpoint, opts RetargetOptions) error {
+	switch {
+	case from.Target == "":
+		return fmt.Errorf("%w: retarget source host is empty", ErrInvalidQuery)
+	case to.Port != 0 && opts.PortShift != 0:
+		return fmt.Errorf("%w: retarget sets a destination port and a port shift", ErrInvalidQuery)
+	case to.Target == "" && to.Port == 0 && opts.PortShift == 0:
+		return fmt.Errorf("%w: retarget changes nothing", ErrInvalidQuery)
+	case opts.PortMin != 0 && opts.PortMax != 0 && opts.PortMin > opts.PortMax:
+		return fmt.Errorf("%w: retarget port range is inverted", ErrInvalidQuery)
+	}
+	return nil
+}
+
+// MatchRetarget reports whether a record is moved by a retarget from the given endpoint
+func MatchRetarget(r *Record, from Endpoint, opts RetargetOptions) bool {
+	switch {
+	case r.Target != from.Target:
+		return false
+	case from.Port != 0 && r.Port != from.Port:
+		return false
+	case opts.InstanceID != 0 && r.InstanceID != opts.InstanceID:
+		return false
+	case opts.DomainPrefix != "" && !strings.HasPrefix(r.Domain, opts.DomainPrefix):
+		return false
+	case opts.PortMin != 0 && r.Port < opts.PortMin:
+		return false
+	case opts.PortMax != 0 && r.Port > opts.PortMax:
+		return false
+	}
+	return true
+}
+
+// RetargetRecord returns a copy of the record pointing to the destination endpoint
+// A record on port 0, the client default port, keeps it unless a destination port is set.
+// It returns an error matching ErrInvalidRecord if a set or shifted port is out of range
+func RetargetRecord(r *Record, to Endpoint, opts RetargetOptions) (*Record, error) {
+	retargeted := r.Clone()
+	if to.Target != "" {
+		retargeted.Target = to.Target
+	}
+
+	port := int64(retargeted.Port)
+	switch {
+	case to.Port != 0:
+		port = int64(to.Port)
+	case opts.PortShift != 0 && port != 0:
+		port += int64(opts.PortShift)
+	default:
+		return retargeted, nil
+	}
+	if port < 1 || port > 65535 {
+		return nil, fmt.Errorf("%w: %s would move to port %d", ErrInvalidRecord, r.Domain, port)
+	}
+	retargeted.Port = int32(port)
+	return retargeted, nil
+}
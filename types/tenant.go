@@ -0,0 +1,41 @@
+package types
+
+import (
+	"context"
+	"fmt"
+)
+
+// MaxTenantLength is the longest tenant name repositories must store
+const MaxTenantLength = 64
+
+type tenantKey struct{}
+
+// WithTenant returns a context scoping repository operations to a tenant
+//
+// Repositories only read, change and delete records of the tenant, and store created
+// records in it. Domains are unique per tenant. Operations without a tenant use the
+// default tenant, the empty string, which holds the records of single tenant deployments
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom returns the tenant set with WithTenant and whether one was set
+func TenantFrom(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(string)
+	return tenant, ok
+}
+
+// AssignTenant puts a record into the tenant of the operation storing it
+// It returns an error matching ErrInvalidRecord if the record belongs to another tenant
+func AssignTenant(r *Record, tenant string) error {
+	switch {
+	case r == nil:
+		return r.Validate()
+	case len(tenant) > MaxTenantLength:
+		return fmt.Errorf("%w: tenant is longer than %d characters", ErrInvalidRecord, MaxTenantLength)
+	case r.Tenant != "" && r.Tenant != tenant:
+		return fmt.Errorf("%w: record of tenant %q stored in tenant %q", ErrInvalidRecord, r.Tenant, tenant)
+	}
+	r.Tenant = tenant
+	return nil
+}
@@ -1,6 +1,10 @@
 package types
 
-import "time"
+import (
+	"context"
+	"maps"
+	"time"
+)
 
 type Record struct {
 	ID         int64
@@ -11,25 +15,224 @@ type Record struct {
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 	DeletedAt  *time.Time
+	// ExpiresAt is the end of the record lease, nil means the record never expires
+	ExpiresAt *time.Time
+	// ValidFrom is when a scheduled record starts answering, nil means immediately
+	ValidFrom *time.Time
+	// ValidUntil is when a scheduled record stops answering, nil means never
+	ValidUntil *time.Time
+	// Version starts at 1 and is incremented by every update or retarget of the record, lease renewals keep it
+	// An update carrying a version other than 0 only applies if it matches the stored one
+	Version int64
+
+	// Labels are free-form key/value pairs, they do not affect routing
+	Labels map[string]string
+	// Description is a free-form note about the record
+	Description string
+	// Owner references who the record belongs to, such as a user or team ID
+	Owner string
+	// Disabled keeps the record without answering it, queries get a miss or the maintenance target
+	Disabled bool
+
+	// AliasOf makes the record an alias answering like the record of the named domain
+	// An alias has no target and port of its own
+	AliasOf string
+
+	// Tenant is the namespace of the record, repositories set it from the context, see WithTenant
+	Tenant string
+}
+
+// Alias reports whether the record is an alias of another domain
+func (r *Record) Alias() bool {
+	return r.AliasOf != ""
+}
+
+// Enabled reports whether the record answers queries with its own target
+func (r *Record) Enabled() bool {
+	return !r.Disabled
+}
+
+// Clone returns a copy of the record that shares no labels with it
+func (r *Record) Clone() *Record {
+	c := *r
+	c.Labels = maps.Clone(r.Labels)
+	return &c
+}
+
+// Expired reports whether the record lease or validity window has ended at the given time
+// An expired record can never become active again
+func (r *Record) Expired(now time.Time) bool {
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(now) {
+		return true
+	}
+	return r.ValidUntil != nil && !r.ValidUntil.After(now)
+}
+
+// Scheduled reports whether the record has a validity window
+func (r *Record) Scheduled() bool {
+	return r.ValidFrom != nil || r.ValidUntil != nil
+}
+
+// ActiveAt reports whether the record answers queries at the given time
+func (r *Record) ActiveAt(now time.Time) bool {
+	if r.DeletedAt != nil || r.Expired(now) {
+		return false
+	}
+	return r.ValidFrom == nil || !r.ValidFrom.After(now)
+}
+
+// SameWindow reports whether two records share the same validity window
+// A domain holds at most one live record per window
+func (r *Record) SameWindow(other *Record) bool {
+	return equalTime(r.ValidFrom, other.ValidFrom) && equalTime(r.ValidUntil, other.ValidUntil)
+}
+
+// Active picks the record answering queries at the given time among records for the same domain
+//
+// Scheduled records take precedence over unscheduled ones, and among scheduled
+// records the one that started last wins. It returns nil if no record is active
+func Active(records []*Record, now time.Time) *Record {
+	var active *Record
+	for _, r := range records {
+		if !r.ActiveAt(now) {
+			continue
+		}
+		if active == nil || precedes(active, r) {
+			active = r
+		}
+	}
+	return active
+}
+
+// precedes reports whether b takes precedence over a
+func precedes(a, b *Record) bool {
+	if a.Scheduled() != b.Scheduled() {
+		return b.Scheduled()
+	}
+	switch {
+	case a.ValidFrom == nil:
+		return b.ValidFrom != nil
+	case b.ValidFrom == nil:
+		return false
+	default:
+		return b.ValidFrom.After(*a.ValidFrom)
+	}
+}
+
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
 }
 
 // RecordRepository defines the interface for record storage
+//
+// Every method except Close takes a context and must give up once it is done,
+// returning ctx.Err(). Implementations return the errors declared in this package,
+// ErrNotFound when a domain, ID or record has no live record, ErrDomainExists on
+// duplicates, ErrInvalidRecord for records failing Record.Validate and ErrClosed
+// after Close. Operations on an instance or on expired records succeed when nothing matches.
+//
+// Implementations of the previous interface without contexts can be adapted with FromLegacy
 type RecordRepository interface {
 	// Find retrieves all records
-	Find() ([]*Record, error)
+	Find(ctx context.Context) ([]*Record, error)
+
+	// List retrieves a page of live records matching the options
+	// It returns ErrInvalidQuery if the options cannot be applied
+	List(ctx context.Context, opts ListOptions) (*RecordPage, error)
+
+	// FindByDomain finds the record currently answering for a domain name
+	FindByDomain(ctx context.Context, domain string) (*Record, error)
 
-	// FindByDomain finds a record by domain name
-	FindByDomain(domain string) (*Record, error)
+	// FindByTarget retrieves the records pointing to a host ordered by ID
+	// Soft-deleted records are included if includeDeleted is set
+	FindByTarget(ctx context.Context, target string, includeDeleted bool) ([]*Record, error)
+
+	// FindByInstanceID retrieves the records of an instance ordered by ID
+	// Soft-deleted records are included if includeDeleted is set
+	FindByInstanceID(ctx context.Context, instanceID int64, includeDeleted bool) ([]*Record, error)
 
 	// Create creates a new record
-	Create(record *Record) error
+	// It returns ErrDomainExists if a live record with the same domain and validity window exists
+	Create(ctx context.Context, record *Record) error
+
+	// Update changes the instance, target, port, lease, validity window and metadata of a live record
+	// The record is identified by its ID, or by domain and validity window if ID is 0.
+	// It returns a *VersionConflictError if record.Version is set and does not match
+	Update(ctx context.Context, record *Record) error
+
+	// Upsert updates the live record with the same domain and validity window, or creates it
+	// Like Update, updating checks record.Version if it is set
+	Upsert(ctx context.Context, record *Record) error
 
-	// Delete removes a record
-	Delete(domain string) error
+	// Retarget points the live records of the from endpoint matching the options to the to endpoint
+	// All records are changed or none is. It returns the diff ordered by record ID
+	Retarget(ctx context.Context, from, to Endpoint, opts RetargetOptions) ([]RecordDiff, error)
+
+	// Delete removes all records of a domain, including scheduled ones
+	Delete(ctx context.Context, domain string) error
+
+	// DeleteByID removes a single record
+	DeleteByID(ctx context.Context, id int64) error
 
 	// DeleteByInstanceID removes all records for a specific instance
-	DeleteByInstanceID(instanceID int64) error
+	DeleteByInstanceID(ctx context.Context, instanceID int64) error
+
+	// Apply applies a batch of changes in order, either all of them or none
+	// The error of a failing change is prefixed with its index in the batch.
+	// Records of a rolled back batch may still have been assigned IDs and timestamps
+	Apply(ctx context.Context, changes []Change) error
+
+	// Renew sets the lease expiry of all records of a domain
+	Renew(ctx context.Context, domain string, expiresAt time.Time) error
+
+	// RenewByInstanceID sets the lease expiry of all records for a specific instance
+	RenewByInstanceID(ctx context.Context, instanceID int64, expiresAt time.Time) error
+
+	// DeleteExpired removes all records whose lease or validity window ended at or before the given time
+	// It returns the number of removed records
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+
+	// ListDeleted retrieves all soft-deleted records
+	ListDeleted(ctx context.Context) ([]*Record, error)
+
+	// Restore undeletes the records of a domain removed by its most recent deletion
+	// It returns the restored records, or ErrDomainExists if the domain was re-created meanwhile
+	Restore(ctx context.Context, domain string) ([]*Record, error)
+
+	// Purge permanently removes records soft-deleted before the given time and their audit entries
+	// It returns the number of purged records
+	Purge(ctx context.Context, olderThan time.Time) (int64, error)
 
 	// Close closes the storage connection
 	Close() error
 }
+
+// ChangeOp identifies the kind of change applied to the records
+type ChangeOp int
+
+const (
+	// ChangeCreate creates Change.Record
+	ChangeCreate ChangeOp = iota + 1
+	// ChangeDelete removes the record for Change.Domain
+	ChangeDelete
+	// ChangeDeleteInstance removes all records for Change.InstanceID
+	ChangeDeleteInstance
+	// ChangeDeleteID removes the single record with Change.ID
+	ChangeDeleteID
+	// ChangeUpdate updates the existing Change.Record
+	ChangeUpdate
+	// ChangeUpsert updates or creates Change.Record
+	ChangeUpsert
+)
+
+// Change describes a single record change applied as part of a batch
+type Change struct {
+	Op         ChangeOp
+	Record     *Record
+	Domain     string
+	InstanceID int64
+	ID         int64
+}
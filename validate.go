@@ -0,0 +1,161 @@
+package tsdns
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+const (
+	// maxHostnameLength is the maximum length of a hostname in its textual form
+	maxHostnameLength = 253
+	// maxLabelLength is the maximum length of a single hostname label
+	maxLabelLength = 63
+	// maxPort is the highest port a record may point to, 0 means the client default port
+	maxPort = 65535
+)
+
+// FieldError describes why a single record field is invalid
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+func (e FieldError) String() string {
+	return e.Field + ": " + e.Reason
+}
+
+// ValidationError lists every invalid field of a record
+// It matches types.ErrInvalidRecord
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	reasons := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		reasons[i] = field.String()
+	}
+	return fmt.Sprintf("%v: %s", types.ErrInvalidRecord, strings.Join(reasons, "; "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return types.ErrInvalidRecord
+}
+
+// add records an invalid field
+func (e *ValidationError) add(field, format string, args ...any) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Reason: fmt.Sprintf(format, args...)})
+}
+
+// ValidateRecord checks the routing fields of a record before it is stored
+//
+// The domain must be a hostname, the target an IP address or a hostname without a port,
+// and the port within 0-65535, where 0 leaves the port to the client. An alias names
+// another hostname instead and has no target or port. It returns a *ValidationError
+// listing every invalid field
+func ValidateRecord(record *types.Record) error {
+	if record == nil {
+		return record.Validate()
+	}
+
+	verr := &ValidationError{}
+	if reason := checkHostname(record.Domain); reason != "" {
+		verr.add("domain", "%s", reason)
+	}
+	if record.Alias() {
+		if reason := checkHostname(record.AliasOf); reason != "" {
+			verr.add("alias_of", "%s", reason)
+		} else if record.AliasOf == record.Domain {
+			verr.add("alias_of", "points to the domain itself")
+		}
+		if record.Target != "" {
+			verr.add("target", "must be empty for an alias")
+		}
+		if record.Port != 0 {
+			verr.add("port", "must be 0 for an alias")
+		}
+	} else {
+		if reason := checkTarget(record.Target); reason != "" {
+			verr.add("target", "%s", reason)
+		}
+		if record.Port < 0 || record.Port > maxPort {
+			verr.add("port", "%d is out of range 0-%d", record.Port, maxPort)
+		}
+	}
+	if record.ValidFrom != nil && record.ValidUntil != nil && !record.ValidUntil.After(*record.ValidFrom) {
+		verr.add("valid_until", "validity window ends before it starts")
+	}
+
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// validateChanges checks the records of a batch of changes
+func validateChanges(changes []types.Change) error {
+	for i, change := range changes {
+		if err := validateChange(change); err != nil {
+			return fmt.Errorf("change %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// validateChange checks the record of a change creating or updating it
+func validateChange(change types.Change) error {
+	switch change.Op {
+	case types.ChangeCreate, types.ChangeUpdate, types.ChangeUpsert:
+		return ValidateRecord(change.Record)
+	}
+	return nil
+}
+
+// checkTarget returns why target is neither an IP address nor a hostname, or an empty string
+func checkTarget(target string) string {
+	if target == "" {
+		return "is empty"
+	}
+	if net.ParseIP(target) != nil {
+		return ""
+	}
+	if host, port, err := net.SplitHostPort(target); err == nil {
+		return fmt.Sprintf("must not include a port, set port %s on the record and target %q", port, host)
+	}
+	return checkHostname(target)
+}
+
+// checkHostname returns why name is not a valid hostname, or an empty string
+func checkHostname(name string) string {
+	switch {
+	case name == "":
+		return "is empty"
+	case len(name) > maxHostnameLength:
+		return fmt.Sprintf("is longer than %d characters", maxHostnameLength)
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		switch {
+		case label == "":
+			return fmt.Sprintf("%q has an empty label", name)
+		case len(label) > maxLabelLength:
+			return fmt.Sprintf("label %q is longer than %d characters", label, maxLabelLength)
+		case label[0] == '-' || label[len(label)-1] == '-':
+			return fmt.Sprintf("label %q starts or ends with a hyphen", label)
+		}
+		for _, c := range label {
+			if !isHostnameChar(c) {
+				return fmt.Sprintf("%q contains invalid character %q", name, c)
+			}
+		}
+	}
+	return ""
+}
+
+// isHostnameChar reports whether c may appear in a hostname label
+func isHostnameChar(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-'
+}
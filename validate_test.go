@@ -0,0 +1,148 @@
+package tsdns
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/honeybbq/tsdns-go/types"
+)
+
+func TestCheckHostname(t *testing.T) {
+	label63 := strings.Repeat("a", maxLabelLength)
+
+	tests := []struct {
+		name  string
+		host  string
+		valid bool
+	}{
+		{"hostname", "play.example.com", true},
+		{"single label", "localhost", true},
+		{"digits and hyphens", "ts-3.example-1.com", true},
+		{"longest label", label63 + ".example.com", true},
+		{"over-long label", label63 + "a.example.com", false},
+		{"longest hostname", strings.Repeat(label63+".", 3) + strings.Repeat("a", 61), true},
+		{"over-long hostname", strings.Repeat(label63+".", 3) + strings.Repeat("a", 62), false},
+		{"empty", "", false},
+		{"leading hyphen", "-play.example.com", false},
+		{"trailing hyphen", "play-.example.com", false},
+		{"trailing dot", "play.example.com.", false},
+		{"leading dot", ".example.com", false},
+		{"empty label", "play..example.com", false},
+		{"underscore", "play_1.example.com", false},
+		{"space", "play example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if reason := checkHostname(tt.host); (reason == "") != tt.valid {
+				t.Fatalf("checkHostname(%q) = %q, want valid %v", tt.host, reason, tt.valid)
+			}
+		})
+	}
+}
+
+func TestCheckTarget(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		valid  bool
+	}{
+		{"IPv4 literal", "192.0.2.1", true},
+		{"IPv6 literal", "2001:db8::1", true},
+		{"IPv4-mapped IPv6 literal", "::ffff:192.0.2.1", true},
+		{"hostname", "ts1.example.com", true},
+		{"empty", "", false},
+		{"IPv4 with port", "192.0.2.1:9987", false},
+		{"bracketed IPv6 with port", "[2001:db8::1]:9987", false},
+		{"hostname with port", "ts1.example.com:9987", false},
+		{"URL", "ts3server://ts1.example.com", false},
+		{"invalid hostname", "ts1-.example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if reason := checkTarget(tt.target); (reason == "") != tt.valid {
+				t.Fatalf("checkTarget(%q) = %q, want valid %v", tt.target, reason, tt.valid)
+			}
+		})
+	}
+}
+
+func TestValidateRecord(t *testing.T) {
+	record := func(domain, target string, port int32) *types.Record {
+		return &types.Record{Domain: domain, Target: target, Port: port}
+	}
+
+	tests := []struct {
+		name   string
+		record *types.Record
+		// fields are the invalid fields reported in order, none if the record is valid
+		fields []string
+	}{
+		{"valid", record("play.example.com", "192.0.2.1", 9987), nil},
+		{"port 0 leaves the port to the client", record("play.example.com", "192.0.2.1", 0), nil},
+		{"highest port", record("play.example.com", "192.0.2.1", 65535), nil},
+		{"port 65536", record("play.example.com", "192.0.2.1", 65536), []string{"port"}},
+		{"negative port", record("play.example.com", "192.0.2.1", -1), []string{"port"}},
+		{"trailing dot", record("play.example.com.", "192.0.2.1", 9987), []string{"domain"}},
+		{"target with port", record("play.example.com", "192.0.2.1:9987", 0), []string{"target"}},
+		{"several bad fields", record("-play.example.com", "", 70000), []string{"domain", "target", "port"}},
+		{"alias", &types.Record{Domain: "ts.example.com", AliasOf: "play.example.com"}, nil},
+		{
+			"alias with target and port",
+			&types.Record{Domain: "ts.example.com", AliasOf: "play.example.com", Target: "192.0.2.1", Port: 9987},
+			[]string{"target", "port"},
+		},
+		{"alias of itself", &types.Record{Domain: "ts.example.com", AliasOf: "ts.example.com"}, []string{"alias_of"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRecord(tt.record)
+			if len(tt.fields) == 0 {
+				if err != nil {
+					t.Fatalf("ValidateRecord error: %v", err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("ValidateRecord error = %v, want a *ValidationError", err)
+			}
+			var fields []string
+			for _, field := range verr.Fields {
+				fields = append(fields, field.Field)
+			}
+			if !slices.Equal(fields, tt.fields) {
+				t.Fatalf("invalid fields = %v, want %v", fields, tt.fields)
+			}
+			if !errors.Is(err, types.ErrInvalidRecord) {
+				t.Fatalf("ValidateRecord error = %v, want it to match ErrInvalidRecord", err)
+			}
+		})
+	}
+}
+
+func TestApplyValidationError(t *testing.T) {
+	s := newTestServer(t, nil)
+
+	err := s.Apply(
+		types.Change{Op: types.ChangeCreate, Record: &types.Record{Domain: "a.example.com", Target: "192.0.2.1", Port: 9987}},
+		types.Change{Op: types.ChangeCreate, Record: &types.Record{Domain: "b.example.com.", Target: "192.0.2.1:9987", Port: 9987}},
+	)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Apply error = %v, want a *ValidationError", err)
+	}
+	if len(verr.Fields) != 2 {
+		t.Fatalf("invalid fields = %v, want domain and target", verr.Fields)
+	}
+	if !strings.HasPrefix(err.Error(), "change 1: ") {
+		t.Fatalf("Apply error = %q, want it attributed to change 1", err)
+	}
+
+	// the valid change of the batch is not stored either
+	if _, ok := s.cache.lookup("a.example.com"); ok {
+		t.Fatalf("a.example.com was stored although the batch was rejected")
+	}
+}